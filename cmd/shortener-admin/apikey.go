@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+)
+
+// apikeyCmd - управление API-ключами: create, list, revoke.
+func (a *app) apikeyCmd(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	store, err := apikey.Open(a.apiKeys)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		fs := a.flagSet("apikey create")
+		owner := fs.String("owner", "", "Owner user ID, empty for a new one")
+		name := fs.String("name", "", "Key description")
+		daily := fs.Int("daily", 0, "Links per day, 0 for unlimited")
+		total := fs.Int("total", 0, "Links in total, 0 for unlimited")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 || *daily < 0 || *total < 0 {
+			return errUsage
+		}
+
+		token, key, err := store.Create(*owner, *name, *daily, *total)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stderr, "created key %s for owner %s, it is shown only once\n", key.ID, key.Owner)
+		fmt.Fprintln(a.stdout, token)
+		return nil
+
+	case "list":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tOWNER\tNAME\tDAILY\tTOTAL\tUSED\tCREATED\tREVOKED")
+		for _, key := range store.List() {
+			revoked := "-"
+			if key.RevokedAt != nil {
+				revoked = key.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+				key.ID, key.Owner, key.Name, key.DailyQuota, key.TotalQuota, key.Usage.Total,
+				key.CreatedAt.Format(time.RFC3339), revoked)
+		}
+		return tw.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return store.Revoke(args[1])
+	}
+	return errUsage
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// Форматы выгрузки.
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+var csvHeader = []string{"short_url", "original_url", "user_id", "deleted", "disabled"}
+
+// line - запись в формате JSONL.
+type line struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+// encodeRecords - запись в CSV или JSONL.
+func encodeRecords(w io.Writer, format string, records []storage.Record) error {
+	switch format {
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, rec := range records {
+			row := []string{rec.ShortURL, rec.OriginalURL, rec.UserID, strconv.FormatBool(rec.DeletedFlag), strconv.FormatBool(rec.Disabled)}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case formatJSONL:
+		enc := json.NewEncoder(w)
+		for _, rec := range records {
+			if err := enc.Encode(line{rec.ShortURL, rec.OriginalURL, rec.UserID, rec.DeletedFlag, rec.Disabled}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// decodeRecords - чтение из CSV или JSONL.
+func decodeRecords(r io.Reader, format string) ([]storage.Record, error) {
+	switch format {
+	case formatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		rows, err := cr.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+
+		var records []storage.Record
+		for i, row := range rows {
+			if i == 0 && len(row) > 0 && row[0] == csvHeader[0] {
+				continue
+			}
+			if len(row) < 2 || len(row) > len(csvHeader) {
+				return nil, fmt.Errorf("line %d: expected 2 to %d columns, got %d", i+1, len(csvHeader), len(row))
+			}
+
+			rec := storage.Record{ShortURL: row[0], OriginalURL: row[1]}
+			if len(row) > 2 {
+				rec.UserID = row[2]
+			}
+			if len(row) > 3 && row[3] != "" {
+				if rec.DeletedFlag, err = strconv.ParseBool(row[3]); err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+			}
+			if len(row) > 4 && row[4] != "" {
+				if rec.Disabled, err = strconv.ParseBool(row[4]); err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+			}
+			records = append(records, rec)
+		}
+		return records, nil
+
+	case formatJSONL:
+		var records []storage.Record
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for n := 1; scanner.Scan(); n++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var l line
+			if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			records = append(records, storage.Record{
+				ShortURL:    l.ShortURL,
+				OriginalURL: l.OriginalURL,
+				UserID:      l.UserID,
+				DeletedFlag: l.Deleted,
+				Disabled:    l.Disabled,
+			})
+		}
+		return records, scanner.Err()
+	}
+
+	return nil, fmt.Errorf("unknown format %q", format)
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// Политики разрешения конфликтов при импорте.
+const (
+	policyFail      = "fail"
+	policySkip      = "skip"
+	policyOverwrite = "overwrite"
+)
+
+// newDB - хранилище с ключами шифрования из глобальных флагов.
+func (a *app) newDB() *storage.DB {
+	db := storage.New()
+	db.SetKeyring(a.keys)
+	return db
+}
+
+// flagSet - набор флагов подкоманды.
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+// export - выгрузка записей в CSV или JSONL.
+func (a *app) export(args []string) error {
+	fs := a.flagSet("export")
+	format := fs.String("format", formatJSONL, "Output format: csv or jsonl")
+	out := fs.String("out", "", "Output file (default: stdout)")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	db := a.newDB()
+	if err := db.LoadFromFile(a.file); err != nil {
+		return fmt.Errorf("load %s: %w", a.file, err)
+	}
+
+	w := a.stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	records := db.Records()
+	if err := encodeRecords(w, *format, records); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stderr, "exported %d records\n", len(records))
+	return nil
+}
+
+// importRecords - загрузка записей из CSV или JSONL с выбранной политикой конфликтов.
+func (a *app) importRecords(args []string) error {
+	fs := a.flagSet("import")
+	format := fs.String("format", formatJSONL, "Input format: csv or jsonl")
+	in := fs.String("in", "", "Input file (default: stdin)")
+	policy := fs.String("policy", policyFail, "On existing key or URL: fail, skip or overwrite")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+	if *policy != policyFail && *policy != policySkip && *policy != policyOverwrite {
+		return errUsage
+	}
+
+	var r io.Reader = a.stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	records, err := decodeRecords(r, *format)
+	if err != nil {
+		return err
+	}
+
+	db := a.newDB()
+	if err := db.LoadFromFile(a.file); err != nil &&
+		!errors.Is(err, os.ErrNotExist) && !errors.Is(err, storage.ErrEmptyFile) {
+		return fmt.Errorf("load %s: %w", a.file, err)
+	}
+
+	var imported, skipped, overwritten int
+	for i, rec := range records {
+		if rec.ShortURL == "" || !validURL(rec.OriginalURL) {
+			return fmt.Errorf("record %d: invalid record %q -> %q", i+1, rec.ShortURL, rec.OriginalURL)
+		}
+
+		// Конфликт - занятый ключ или действующий URL, уже сокращённый под другим ключом
+		var conflict error
+		if _, exists := db.Get(rec.ShortURL); exists {
+			conflict = fmt.Errorf("record %d: key %q already exists", i+1, rec.ShortURL)
+		}
+		owner, err := db.FindByURL(context.Background(), rec.OriginalURL)
+		urlTaken := err == nil && !rec.DeletedFlag && owner.ShortURL != rec.ShortURL
+		if urlTaken && conflict == nil {
+			conflict = fmt.Errorf("record %d: URL %q already exists under key %q", i+1, rec.OriginalURL, owner.ShortURL)
+		}
+
+		if conflict != nil {
+			switch *policy {
+			case policyFail:
+				return conflict
+			case policySkip:
+				skipped++
+				continue
+			case policyOverwrite:
+				overwritten++
+			}
+		} else {
+			imported++
+		}
+		// URL может быть действующим только под одним ключом, прежняя ссылка помечается удалённой
+		if urlTaken {
+			if err := db.MarkDeleted(context.Background(), owner.ShortURL); err != nil {
+				return err
+			}
+		}
+		db.Put(rec)
+	}
+
+	if err := db.SaveToFile(a.file); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "imported %d, overwritten %d, skipped %d\n", imported, overwritten, skipped)
+	return nil
+}
+
+// verify - проверка файла: контрольная сумма, пустые и повторяющиеся ключи, некорректные URL.
+func (a *app) verify(args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	problems := 0
+	report := func(format string, args ...any) {
+		problems++
+		fmt.Fprintf(a.stdout, format+"\n", args...)
+	}
+
+	records, version, err := storage.ReadFile(a.file, a.keys)
+	if errors.Is(err, storage.ErrChecksum) {
+		report("%v", err) // записи прочитаны, проверяем их дальше
+	} else if err != nil {
+		return fmt.Errorf("read %s: %w", a.file, err)
+	}
+
+	keys := make(map[string]int)
+	urls := make(map[string]string)
+	for i, rec := range records {
+		n := i + 1
+		if rec.ShortURL == "" {
+			report("record %d: empty key", n)
+		} else if first, ok := keys[rec.ShortURL]; ok {
+			report("record %d: duplicate key %q (first seen in record %d)", n, rec.ShortURL, first)
+		} else {
+			keys[rec.ShortURL] = n
+		}
+
+		if !validURL(rec.OriginalURL) {
+			report("record %d: invalid URL %q", n, rec.OriginalURL)
+		}
+
+		// Одинаковые URL под разными ключами допустимы, но нарушают уникальность при сокращении
+		if !rec.DeletedFlag {
+			if key, ok := urls[rec.OriginalURL]; ok {
+				fmt.Fprintf(a.stdout, "record %d: warning: URL %q is also stored under key %q\n", n, rec.OriginalURL, key)
+			} else {
+				urls[rec.OriginalURL] = rec.ShortURL
+			}
+		}
+	}
+
+	fmt.Fprintf(a.stdout, "format v%d, %d records, %d problems\n", version, len(records), problems)
+	if problems > 0 {
+		return errVerify
+	}
+	return nil
+}
+
+// migrate - перезапись файла в другой версии формата.
+func (a *app) migrate(args []string) error {
+	fs := a.flagSet("migrate")
+	to := fs.Int("to", storage.CurrentFormat, "Target format version")
+	out := fs.String("out", "", "Output file (default: overwrite input)")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	records, version, err := storage.ReadFile(a.file, a.keys)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", a.file, err)
+	}
+
+	target := *out
+	if target == "" {
+		target = a.file
+	}
+	if err := storage.WriteFile(target, records, *to, a.keys); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "migrated %d records from v%d to v%d\n", len(records), version, *to)
+	return nil
+}
+
+// reencrypt - перешифрование файла новым основным ключом.
+// Файл читается текущими и новыми ключами, записывается новым основным; -plain снимает шифрование.
+func (a *app) reencrypt(args []string) error {
+	fs := a.flagSet("reencrypt")
+	newKey := fs.String("new-key", "", "New key <id>:<base64>")
+	newKeyFile := fs.String("new-key-file", "", "New key file, first key becomes primary")
+	plain := fs.Bool("plain", false, "Write the file unencrypted")
+	out := fs.String("out", "", "Output file (default: overwrite input)")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	newKeys, err := storage.LoadKeyring(*newKey, *newKeyFile)
+	if err != nil {
+		return fmt.Errorf("new key: %w", err)
+	}
+	if (newKeys == nil) == !*plain {
+		return errUsage // нужен либо новый ключ, либо -plain
+	}
+
+	readKeys := storage.NewKeyring()
+	readKeys.Merge(a.keys)
+	readKeys.Merge(newKeys)
+
+	records, version, err := storage.ReadFile(a.file, readKeys)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", a.file, err)
+	}
+	if version < storage.FormatV2 {
+		version = storage.FormatV2 // шифрование доступно начиная с v2
+	}
+
+	target := *out
+	if target == "" {
+		target = a.file
+	}
+	if err := storage.WriteFile(target, records, version, newKeys); err != nil {
+		return err
+	}
+
+	if newKeys == nil {
+		fmt.Fprintf(a.stdout, "decrypted %d records\n", len(records))
+	} else {
+		fmt.Fprintf(a.stdout, "re-encrypted %d records with key %q\n", len(records), newKeys.Primary())
+	}
+	return nil
+}
+
+// keygen - генерация ключа шифрования.
+func (a *app) keygen(args []string) error {
+	fs := a.flagSet("keygen")
+	id := fs.String("id", "", "Key id")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *id == "" {
+		return errUsage
+	}
+
+	key, err := storage.GenerateKey(*id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stdout, key)
+	return nil
+}
+
+// validURL - абсолютный http(s) URL с хостом.
+func validURL(rawURL string) bool {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+const usage = `Usage: shortener-admin [-file path] <command> [flags]
+
+Работает с файлом хранилища напрямую, сервер должен быть остановлен.
+
+Commands:
+  export    выгрузить записи в CSV или JSONL
+  import    загрузить записи из CSV или JSONL
+  verify    проверить целостность файла
+  migrate   перевести файл в другую версию формата
+  reencrypt перешифровать файл новым ключом
+  keygen    сгенерировать ключ шифрования
+  apikey    управлять API-ключами: create, list, revoke <id> (сервер можно не останавливать)
+
+Global flags:
+`
+
+// errUsage - ошибка в аргументах командной строки.
+var errUsage = errors.New("invalid usage")
+
+// errVerify - файл не прошёл проверку.
+var errVerify = errors.New("verification failed")
+
+// app - окружение запуска команды.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	file    string
+	key     string
+	keyFile string
+	keys    *storage.Keyring
+	apiKeys string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run - разбор аргументов и выполнение команды, возвращает код выхода.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	a := &app{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	defaultFile := os.Getenv("FILE_STORAGE_PATH")
+	if defaultFile == "" {
+		defaultFile = "data/db.json"
+	}
+
+	fs := flag.NewFlagSet("shortener-admin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&a.file, "file", defaultFile, "Storage file")
+	fs.StringVar(&a.key, "key", os.Getenv("STORAGE_KEY"), "Storage encryption key <id>:<base64>")
+	fs.StringVar(&a.keyFile, "key-file", os.Getenv("STORAGE_KEY_FILE"), "Storage encryption key file")
+	defaultAPIKeys := os.Getenv("API_KEYS_FILE")
+	if defaultAPIKeys == "" {
+		defaultAPIKeys = "data/apikeys.json"
+	}
+	fs.StringVar(&a.apiKeys, "api-keys", defaultAPIKeys, "API keys file")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	commands := map[string]func([]string) error{
+		"export":    a.export,
+		"import":    a.importRecords,
+		"verify":    a.verify,
+		"migrate":   a.migrate,
+		"reencrypt": a.reencrypt,
+		"keygen":    a.keygen,
+		"apikey":    a.apikeyCmd,
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	var err error
+	if a.keys, err = storage.LoadKeyring(a.key, a.keyFile); err != nil {
+		fmt.Fprintf(stderr, "error: storage key: %v\n", err)
+		return 1
+	}
+
+	if err := cmd(fs.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fs.Usage()
+			return 2
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
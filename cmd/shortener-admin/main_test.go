@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.json")
+
+	admin := func(stdin string, args ...string) (string, int) {
+		var stdout, stderr bytes.Buffer
+		code := run(append([]string{"-file", file}, args...), strings.NewReader(stdin), &stdout, &stderr)
+		return stdout.String() + stderr.String(), code
+	}
+
+	t.Run("import into new file", func(t *testing.T) {
+		in := "short_url,original_url,user_id,deleted\nkey1,https://example.com,user1,false\nkey2,https://google.com,,true\n"
+		out, code := admin(in, "import", "-format", "csv")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "imported 2")
+
+		db := storage.New()
+		require.NoError(t, db.LoadFromFile(file))
+		require.Equal(t, []storage.Record{
+			{ShortURL: "key1", OriginalURL: "https://example.com", UserID: "user1"},
+			{ShortURL: "key2", OriginalURL: "https://google.com", DeletedFlag: true},
+		}, db.Records())
+	})
+
+	t.Run("import conflict policies", func(t *testing.T) {
+		in := `{"short_url":"key1","original_url":"https://github.com"}` + "\n"
+
+		out, code := admin(in, "import")
+		require.Equal(t, 1, code, out)
+		require.Contains(t, out, "already exists")
+
+		out, code = admin(in, "import", "-policy", "skip")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "skipped 1")
+
+		out, code = admin(in, "import", "-policy", "overwrite")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "overwritten 1")
+
+		db := storage.New()
+		require.NoError(t, db.LoadFromFile(file))
+		value, _ := db.Get("key1")
+		require.Equal(t, "https://github.com", value)
+	})
+
+	t.Run("import URL conflict policies", func(t *testing.T) {
+		conflicts := filepath.Join(t.TempDir(), "db.json")
+		importInto := func(stdin string, args ...string) (string, int) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"-file", conflicts, "import"}, args...), strings.NewReader(stdin), &stdout, &stderr)
+			return stdout.String() + stderr.String(), code
+		}
+		out, code := importInto(`{"short_url":"key1","original_url":"https://github.com"}` + "\n")
+		require.Equal(t, 0, code, out)
+
+		in := `{"short_url":"key9","original_url":"https://github.com"}` + "\n"
+
+		out, code = importInto(in)
+		require.Equal(t, 1, code, out)
+		require.Contains(t, out, `under key "key1"`)
+
+		out, code = importInto(in, "-policy", "skip")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "skipped 1")
+
+		out, code = importInto(in, "-policy", "overwrite")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "overwritten 1")
+
+		db := storage.New()
+		require.NoError(t, db.LoadFromFile(conflicts))
+		rec, err := db.FindByURL(context.Background(), "https://github.com")
+		require.NoError(t, err)
+		require.Equal(t, "key9", rec.ShortURL)
+		old, err := db.Find(context.Background(), "key1")
+		require.NoError(t, err)
+		require.True(t, old.DeletedFlag)
+	})
+
+	t.Run("import into empty file", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty.json")
+		require.NoError(t, os.WriteFile(empty, nil, 0o644))
+
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-file", empty, "import"}, strings.NewReader(`{"short_url":"a","original_url":"https://example.com"}`+"\n"), &stdout, &stderr)
+		require.Equal(t, 0, code, stderr.String())
+		require.Contains(t, stdout.String(), "imported 1")
+	})
+
+	t.Run("import rejects invalid URL", func(t *testing.T) {
+		out, code := admin("key3,not a url\n", "import", "-format", "csv")
+		require.Equal(t, 1, code, out)
+	})
+
+	t.Run("export", func(t *testing.T) {
+		out, code := admin("", "export", "-format", "csv")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "key1,https://github.com,,false")
+
+		out, code = admin("", "export")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, `{"short_url":"key2","original_url":"https://google.com","deleted":true}`)
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		out, code := admin("", "verify")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "0 problems")
+
+		broken := filepath.Join(t.TempDir(), "broken.json")
+		require.NoError(t, os.WriteFile(broken, []byte(`[
+			{"ID":1,"ShortURL":"a","OriginalURL":"https://example.com"},
+			{"ID":2,"ShortURL":"a","OriginalURL":"http://invalid url"}
+		]`), 0644))
+
+		var stdout bytes.Buffer
+		code = run([]string{"-file", broken, "verify"}, nil, &stdout, &stdout)
+		require.Equal(t, 1, code)
+		require.Contains(t, stdout.String(), `duplicate key "a"`)
+		require.Contains(t, stdout.String(), "invalid URL")
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		tampered := filepath.Join(t.TempDir(), "tampered.json")
+		require.NoError(t, os.WriteFile(tampered, bytes.Replace(data, []byte("google"), []byte("g00gle"), 1), 0644))
+
+		stdout.Reset()
+		code = run([]string{"-file", tampered, "verify"}, nil, &stdout, &stdout)
+		require.Equal(t, 1, code)
+		require.Contains(t, stdout.String(), "checksum mismatch")
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		target := filepath.Join(t.TempDir(), "migrated.json")
+		out, code := admin("", "migrate", "-to", "1", "-out", target)
+		require.Equal(t, 0, code, out)
+
+		records, version, err := storage.ReadFile(target, nil)
+		require.NoError(t, err)
+		require.Equal(t, storage.FormatV1, version)
+		require.Len(t, records, 2)
+
+		var stdout bytes.Buffer
+		code = run([]string{"-file", target, "migrate"}, nil, &stdout, &stdout)
+		require.Equal(t, 0, code, stdout.String())
+		require.Contains(t, stdout.String(), "from v1 to v2")
+
+		_, version, err = storage.ReadFile(target, nil)
+		require.NoError(t, err)
+		require.Equal(t, storage.FormatV2, version)
+
+		out, code = admin("", "migrate", "-to", "99", "-out", target)
+		require.Equal(t, 1, code, out)
+	})
+
+	t.Run("encryption", func(t *testing.T) {
+		out, code := admin("", "keygen", "-id", "k1")
+		require.Equal(t, 0, code, out)
+		k1 := strings.TrimSpace(out)
+		out, code = admin("", "keygen", "-id", "k2")
+		require.Equal(t, 0, code, out)
+		k2 := strings.TrimSpace(out)
+
+		out, code = admin("", "reencrypt", "-new-key", k1)
+		require.Equal(t, 0, code, out)
+
+		_, code = admin("", "export")
+		require.Equal(t, 1, code)
+		out, code = admin("", "-key", k1, "export")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "https://github.com")
+
+		out, code = admin("", "-key", k1, "reencrypt", "-new-key", k2)
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, `key "k2"`)
+
+		_, code = admin("", "-key", k1, "verify")
+		require.Equal(t, 1, code)
+		out, code = admin("", "-key", k2, "verify")
+		require.Equal(t, 0, code, out)
+
+		out, code = admin("", "-key", k2, "reencrypt", "-plain")
+		require.Equal(t, 0, code, out)
+		out, code = admin("", "verify")
+		require.Equal(t, 0, code, out)
+	})
+
+	t.Run("apikey", func(t *testing.T) {
+		keysFile := filepath.Join(t.TempDir(), "apikeys.json")
+
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-api-keys", keysFile, "apikey", "create", "-owner", "service", "-total", "10"},
+			strings.NewReader(""), &stdout, &stderr)
+		require.Equal(t, 0, code, stderr.String())
+		token := strings.TrimSpace(stdout.String())
+
+		keys, err := apikey.Open(keysFile)
+		require.NoError(t, err)
+		key, err := keys.Authenticate(token)
+		require.NoError(t, err)
+		require.Equal(t, "service", key.Owner)
+
+		out, code := admin("", "-api-keys", keysFile, "apikey", "list")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, key.ID)
+		require.NotContains(t, out, token)
+
+		out, code = admin("", "-api-keys", keysFile, "apikey", "revoke", key.ID)
+		require.Equal(t, 0, code, out)
+
+		keys, err = apikey.Open(keysFile)
+		require.NoError(t, err)
+		_, err = keys.Authenticate(token)
+		require.ErrorIs(t, err, apikey.ErrInvalid)
+
+		_, code = admin("", "-api-keys", keysFile, "apikey", "create", "-daily", "-1")
+		require.Equal(t, 2, code)
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		_, code := admin("")
+		require.Equal(t, 2, code)
+
+		_, code = admin("", "import", "-policy", "merge")
+		require.Equal(t, 2, code)
+
+		_, code = admin("", "reencrypt")
+		require.Equal(t, 2, code)
+	})
+}
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
 
+	"github.com/ParkhomenkoDV/URLShortener/internal/accesslog"
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
 	"github.com/ParkhomenkoDV/URLShortener/internal/config"
-	"github.com/ParkhomenkoDV/URLShortener/internal/handler"
 	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
-	"github.com/ParkhomenkoDV/URLShortener/internal/middleware"
+	"github.com/ParkhomenkoDV/URLShortener/internal/metrics"
+	"github.com/ParkhomenkoDV/URLShortener/internal/router"
 	"github.com/ParkhomenkoDV/URLShortener/internal/server"
 	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
-	"github.com/go-chi/chi/v5"
-	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage/bloom"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage/cache"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage/kv"
+	"github.com/ParkhomenkoDV/URLShortener/internal/tracing"
 )
 
 func main() {
@@ -20,32 +29,161 @@ func main() {
 		log.Fatalf("config error: %v", err)
 	}
 
-	logger.New()
+	if err := logger.New(logger.Config{Level: cfg.LogLevel, Format: cfg.LogFormat, Output: cfg.LogFile}); err != nil {
+		log.Fatalf("logger error: %v", err)
+	}
+	defer logger.Sync()
 
-	db := storage.New()
-	db.LoadFromFile(cfg.FileStorage)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.TraceExporter,
+		File:        cfg.TraceFile,
+		Endpoint:    cfg.TraceEndpoint,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("tracing error: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Без заданного ключа секрет сохраняется рядом с хранилищем, иначе cookie теряются при перезапуске
+	if cfg.SecretKey == "" {
+		cfg.SecretKey, err = auth.LoadSecret(secretPath(&cfg))
+		if err != nil {
+			log.Fatalf("secret key error: %v", err)
+		}
+	}
+
+	db, err := openStorage(&cfg)
+	if err != nil {
+		log.Fatalf("storage error: %v", err)
+	}
+	if cfg.TraceExporter != tracing.ExporterNone {
+		db = tracing.Instrument(db)
+	}
+
+	// Метрики собираются, только если есть служебный listener для их отдачи
+	var m *metrics.Metrics
+	var routerOpts []router.Option
+	if cfg.AdminAddress != "" {
+		m = metrics.New()
+		db = m.Instrument(db)
+		routerOpts = append(routerOpts, router.WithMetrics(m))
+	}
 
-	hand := handler.New(&cfg, db)
+	if cfg.CacheSize > 0 {
+		c := cache.New(db, cfg.CacheSize, cfg.CacheTTL)
+		if m != nil {
+			m.ObserveCache(c)
+		}
+		db = c
+	}
+	if cfg.BloomFPRate > 0 {
+		g, err := bloom.New(context.Background(), db, cfg.BloomFPRate)
+		if err != nil {
+			log.Fatalf("bloom filter error: %v", err)
+		}
+		if m != nil {
+			m.ObserveBloom(g)
+		}
+		db = g
+	}
 
-	r := chi.NewRouter()
-	r.Use(middleware.GzipRequestMiddleware)
-	r.Use(middleware.GzipResponseMiddleware)
-	r.Use(chiMiddleware.Logger)
-	r.Use(chiMiddleware.Recoverer)
-	r.Use(logger.LoggingMiddleware)
+	keys, err := apikey.Open(cfg.APIKeysFile)
+	if err != nil {
+		log.Fatalf("API keys error: %v", err)
+	}
+	routerOpts = append(routerOpts, router.WithAPIKeys(keys))
 
-	r.Post("/", hand.Post)
-	r.Post("/api/shorten", hand.PostJSON)
-	r.Get("/{id}", hand.Get)
+	if cfg.AuditLog != "" {
+		auditLog, err := audit.Open(cfg.AuditLog)
+		if err != nil {
+			log.Fatalf("audit log error: %v", err)
+		}
+		defer auditLog.Close()
+		routerOpts = append(routerOpts, router.WithAudit(auditLog))
+	}
 
-	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	})
+	if cfg.AccessLog != "" {
+		out, err := accesslog.OpenRotator(cfg.AccessLog, cfg.AccessLogMaxSize, cfg.AccessLogMaxInterval, cfg.AccessLogKeep)
+		if err != nil {
+			log.Fatalf("access log error: %v", err)
+		}
+		defer out.Close()
+
+		access, err := accesslog.New(out, cfg.AccessLogFormat)
+		if err != nil {
+			log.Fatalf("access log error: %v", err)
+		}
+		routerOpts = append(routerOpts, router.WithAccessLog(access))
+	}
+
+	if cfg.PendingPage != "" {
+		page, err := os.ReadFile(cfg.PendingPage)
+		if err != nil {
+			log.Fatalf("pending page error: %v", err)
+		}
+		routerOpts = append(routerOpts, router.WithPendingPage(page))
+	}
+
+	r := router.New(&cfg, db, routerOpts...)
 
 	srv := server.New(&cfg, r, db)
+	if m != nil {
+		m.ObserveStorage(db)
+
+		admin := http.NewServeMux()
+		admin.Handle("/metrics", m.Handler())
+		srv.WithAdmin(admin)
+	}
 	if err := srv.Start(); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 
-	log.Println("Server ended")
+	logger.L().Info("server ended")
+}
+
+// secretPath - файл сгенерированного секрета cookie рядом с файлом хранилища.
+func secretPath(cfg *config.Config) string {
+	if cfg.Backend == config.BackendKV {
+		return cfg.KVStorage + ".secret"
+	}
+	return cfg.FileStorage + ".secret"
+}
+
+// openStorage - открытие хранилища выбранного бэкенда.
+func openStorage(cfg *config.Config) (storage.Store, error) {
+	keys, err := storage.LoadKeyring(cfg.StorageKey, cfg.StorageKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Backend == config.BackendKV {
+		store, err := kv.Open(cfg.KVStorage, kv.WithKeyring(keys))
+		if err != nil {
+			return nil, err
+		}
+
+		// При первом запуске переносим данные из JSON-файла
+		migrated, err := store.MigrateFrom(cfg.FileStorage, keys)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		if migrated > 0 {
+			logger.L().Infow("migrated records", "count", migrated, "from", cfg.FileStorage, "to", cfg.KVStorage)
+		}
+		return store, nil
+	}
+
+	db := storage.New()
+	db.SetKeyring(keys)
+	if err := db.LoadFromFile(cfg.FileStorage); err != nil &&
+		!errors.Is(err, os.ErrNotExist) && !errors.Is(err, storage.ErrEmptyFile) {
+		if !cfg.ForceStorage {
+			log.Fatalf("load storage %s: %v (use -force-storage to start empty and overwrite it)", cfg.FileStorage, err)
+		}
+		logger.L().Warnw("load storage failed, starting empty", "file", cfg.FileStorage, "error", err)
+		db.AllowOverwrite(cfg.FileStorage)
+	}
+	return db, nil
 }
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ParkhomenkoDV/URLShortener/pkg/client"
+)
+
+// session - клиент и профиль, из которого он создан.
+type session struct {
+	*client.Client
+	profiles *Profiles
+	profile  *Profile
+}
+
+// connect - создание клиента по профилю с учётом глобальных флагов.
+func (a *app) connect() (*session, error) {
+	profiles, err := loadProfiles(a.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("load profiles: %w", err)
+	}
+	profile := profiles.get(a.profileName)
+
+	server, apiKey, adminToken := profile.Server, profile.APIKey, profile.AdminToken
+	if a.server != "" {
+		server = a.server
+	}
+	if a.apiKey != "" {
+		apiKey = a.apiKey
+	}
+	if a.adminToken != "" {
+		adminToken = a.adminToken
+	}
+
+	var opts []client.Option
+	if apiKey != "" {
+		opts = append(opts, client.WithAPIKey(apiKey))
+	} else if profile.Cookie != "" {
+		opts = append(opts, client.WithAuthCookie(profile.Cookie))
+	}
+	if adminToken != "" {
+		opts = append(opts, client.WithAdminToken(adminToken))
+	}
+
+	return &session{Client: client.New(server, opts...), profiles: profiles, profile: profile}, nil
+}
+
+// close - сохранение выданной сервером cookie, чтобы следующие запуски работали от того же пользователя.
+func (a *app) close(s *session) {
+	if s.profile.APIKey != "" || a.apiKey != "" || a.server != "" {
+		return
+	}
+	cookie := s.AuthCookie()
+	if cookie == "" || cookie == s.profile.Cookie {
+		return
+	}
+
+	s.profile.Cookie = cookie
+	if err := s.profiles.save(a.configPath); err != nil {
+		fmt.Fprintf(a.stderr, "warning: save profile: %v\n", err)
+	}
+}
+
+func (a *app) shorten(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	s, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer a.close(s)
+
+	type result struct {
+		OriginalURL string `json:"original_url"`
+		ShortURL    string `json:"short_url"`
+		Existing    bool   `json:"existing"`
+	}
+	results := make([]result, 0, len(args))
+	rows := make([][]string, 0, len(args))
+	for _, originalURL := range args {
+		shortURL, err := s.Shorten(ctx, originalURL)
+		existing := errors.Is(err, client.ErrConflict)
+		if err != nil && !existing {
+			return fmt.Errorf("shorten %s: %w", originalURL, err)
+		}
+
+		results = append(results, result{originalURL, shortURL, existing})
+		rows = append(rows, []string{shortURL, originalURL, strconv.FormatBool(existing)})
+	}
+
+	return a.print(results, []string{"SHORT_URL", "ORIGINAL_URL", "EXISTING"}, rows)
+}
+
+func (a *app) expand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	s, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer a.close(s)
+
+	var results []client.URL
+	var rows [][]string
+	for _, shortURL := range args {
+		originalURL, err := s.Expand(ctx, shortURL)
+		if err != nil {
+			return fmt.Errorf("expand %s: %w", shortURL, err)
+		}
+
+		results = append(results, client.URL{ShortURL: shortURL, OriginalURL: originalURL})
+		rows = append(rows, []string{shortURL, originalURL})
+	}
+
+	return a.print(results, []string{"SHORT_URL", "ORIGINAL_URL"}, rows)
+}
+
+func (a *app) batch(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	file := fs.String("file", "", "Input file (default: stdin)")
+	asCSV := fs.Bool("csv", false, "Input is CSV with columns correlation_id,original_url")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	in := a.stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var items []client.BatchItem
+	var err error
+	if *asCSV {
+		items, err = readBatchCSV(in)
+	} else {
+		items, err = readBatchLines(in)
+	}
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return errors.New("no URLs in input")
+	}
+
+	s, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer a.close(s)
+
+	results, err := s.ShortenBatch(ctx, items)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(results))
+	for i, res := range results {
+		rows[i] = []string{res.CorrelationID, res.ShortURL}
+	}
+	return a.print(results, []string{"CORRELATION_ID", "SHORT_URL"}, rows)
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	s, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer a.close(s)
+
+	urls, err := s.ListMine(ctx)
+	if err != nil {
+		return err
+	}
+	if urls == nil {
+		urls = []client.URL{}
+	}
+
+	rows := make([][]string, len(urls))
+	for i, u := range urls {
+		rows[i] = []string{u.ShortURL, u.OriginalURL}
+	}
+	return a.print(urls, []string{"SHORT_URL", "ORIGINAL_URL"}, rows)
+}
+
+func (a *app) delete(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	s, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer a.close(s)
+
+	keys := make([]string, len(args))
+	for i, arg := range args {
+		keys[i] = arg[strings.LastIndex(arg, "/")+1:] // принимаются и ключи, и полные ссылки
+	}
+
+	if err := s.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(keys))
+	for i, key := range keys {
+		rows[i] = []string{key}
+	}
+	return a.print(map[string][]string{"deleted": keys}, []string{"DELETED"}, rows)
+}
+
+func (a *app) stats(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	s, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer a.close(s)
+
+	stats, err := s.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.print(stats, []string{"URLS", "USERS"},
+		[][]string{{strconv.Itoa(stats.URLs), strconv.Itoa(stats.Users)}})
+}
+
+// profile - "profile" показывает профиль, "profile set" меняет его, "profile use" выбирает текущий.
+func (a *app) profile(_ context.Context, args []string) error {
+	profiles, err := loadProfiles(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	name := a.profileName
+	if name == "" {
+		name = profiles.Current
+	}
+
+	switch {
+	case len(args) == 0:
+		p := profiles.get(name)
+		return a.print(map[string]any{"name": name, "server": p.Server, "api_key_set": p.APIKey != ""},
+			[]string{"NAME", "SERVER", "API_KEY"},
+			[][]string{{name, p.Server, strconv.FormatBool(p.APIKey != "")}})
+
+	case args[0] == "set":
+		fs := flag.NewFlagSet("profile set", flag.ContinueOnError)
+		fs.SetOutput(a.stderr)
+		server := fs.String("server", "", "Server URL")
+		apiKey := fs.String("api-key", "", "API key")
+		adminToken := fs.String("admin-token", "", "Admin token")
+		if err := fs.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+
+		p := profiles.get(name)
+		if *server != "" && *server != p.Server {
+			p.Server = *server
+			p.Cookie = "" // cookie выдана другим сервером
+		}
+		if *apiKey != "" {
+			p.APIKey = *apiKey
+		}
+		if *adminToken != "" {
+			p.AdminToken = *adminToken
+		}
+		return profiles.save(a.configPath)
+
+	case args[0] == "use" && len(args) == 2:
+		profiles.get(args[1])
+		profiles.Current = args[1]
+		return profiles.save(a.configPath)
+	}
+
+	return errUsage
+}
+
+// readBatchLines - один URL на строку, идентификатор - номер строки.
+func readBatchLines(r io.Reader) ([]client.BatchItem, error) {
+	var items []client.BatchItem
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		originalURL := strings.TrimSpace(scanner.Text())
+		if originalURL == "" || strings.HasPrefix(originalURL, "#") {
+			continue
+		}
+		items = append(items, client.BatchItem{CorrelationID: strconv.Itoa(line), OriginalURL: originalURL})
+	}
+	return items, scanner.Err()
+}
+
+// readBatchCSV - CSV с колонками correlation_id,original_url и необязательным заголовком.
+func readBatchCSV(r io.Reader) ([]client.BatchItem, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []client.BatchItem
+	for i, rec := range records {
+		if len(rec) != 2 {
+			return nil, fmt.Errorf("line %d: expected 2 columns, got %d", i+1, len(rec))
+		}
+		if i == 0 && rec[0] == "correlation_id" {
+			continue
+		}
+		items = append(items, client.BatchItem{CorrelationID: rec[0], OriginalURL: strings.TrimSpace(rec[1])})
+	}
+	return items, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+const usage = `Usage: shortenerctl [global flags] <command> [flags] [args]
+
+Commands:
+  shorten <url>...      сократить URL
+  expand <short>...     получить исходный URL
+  batch                 пакетное сокращение из stdin или файла (строки или CSV)
+  list                  ссылки текущего пользователя
+  delete <key>...       удалить ссылки
+  stats                 статистика сервиса (нужен токен администратора)
+  profile               показать или изменить профиль
+
+Global flags:
+`
+
+// errUsage - ошибка в аргументах командной строки.
+var errUsage = errors.New("invalid usage")
+
+// app - окружение запуска команды.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath  string
+	profileName string
+	server      string
+	apiKey      string
+	adminToken  string
+	output      string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run - разбор аргументов и выполнение команды, возвращает код выхода.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	a := &app{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("shortenerctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&a.configPath, "config", defaultConfigPath(), "Profiles file")
+	fs.StringVar(&a.profileName, "profile", "", "Profile name (default: current profile)")
+	fs.StringVar(&a.server, "server", "", "Server URL, overrides profile")
+	fs.StringVar(&a.apiKey, "api-key", "", "API key, overrides profile")
+	fs.StringVar(&a.adminToken, "admin-token", "", "Admin token for stats, overrides profile")
+	fs.StringVar(&a.output, "o", "table", "Output format: table or json")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || (a.output != "table" && a.output != "json") {
+		fs.Usage()
+		return 2
+	}
+
+	commands := map[string]func(context.Context, []string) error{
+		"shorten": a.shorten,
+		"expand":  a.expand,
+		"batch":   a.batch,
+		"list":    a.list,
+		"delete":  a.delete,
+		"stats":   a.stats,
+		"profile": a.profile,
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	if err := cmd(ctx, fs.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fs.Usage()
+			return 2
+		}
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+	"github.com/ParkhomenkoDV/URLShortener/internal/router"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortenerctl(t *testing.T) {
+	require.NoError(t, logger.New(logger.Config{Level: "error"}))
+
+	srv := httptest.NewUnstartedServer(nil)
+	cfg := &config.Config{BaseURL: "http://" + srv.Listener.Addr().String(), SecretKey: "secret", AdminToken: "admin"}
+	srv.Config.Handler = router.New(cfg, storage.New())
+	srv.Start()
+	defer srv.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	ctl := func(stdin string, args ...string) (string, int) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), append([]string{"-config", configPath}, args...),
+			strings.NewReader(stdin), &stdout, &stderr)
+		return stdout.String() + stderr.String(), code
+	}
+
+	out, code := ctl("", "profile", "set", "-server", srv.URL)
+	require.Equal(t, 0, code, out)
+
+	t.Run("shorten and expand", func(t *testing.T) {
+		out, code := ctl("", "-o", "json", "shorten", "https://example.com")
+		require.Equal(t, 0, code, out)
+
+		var results []struct {
+			ShortURL string `json:"short_url"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(out), &results))
+		require.Len(t, results, 1)
+
+		out, code = ctl("", "expand", results[0].ShortURL)
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "https://example.com")
+	})
+
+	t.Run("batch from CSV", func(t *testing.T) {
+		out, code := ctl("correlation_id,original_url\na,https://google.com\nb,https://github.com\n", "batch", "-csv")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "CORRELATION_ID")
+		require.Contains(t, out, srv.URL+"/")
+	})
+
+	t.Run("list keeps user between runs", func(t *testing.T) {
+		out, code := ctl("", "-o", "json", "list")
+		require.Equal(t, 0, code, out)
+
+		var urls []struct {
+			ShortURL string `json:"short_url"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(out), &urls))
+		require.Len(t, urls, 3)
+
+		out, code = ctl("", "delete", urls[0].ShortURL)
+		require.Equal(t, 0, code, out)
+
+		out, code = ctl("", "stats")
+		require.Equal(t, 1, code, out)
+
+		out, code = ctl("", "-admin-token", "admin", "stats")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "URLS")
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		_, code := ctl("")
+		require.Equal(t, 2, code)
+
+		_, code = ctl("", "unknown")
+		require.Equal(t, 2, code)
+
+		_, code = ctl("", "shorten")
+		require.Equal(t, 2, code)
+	})
+}
+
+func TestReadBatch(t *testing.T) {
+	items, err := readBatchLines(strings.NewReader("https://a.example\n\n# comment\nhttps://b.example\n"))
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "4", items[1].CorrelationID)
+
+	_, err = readBatchCSV(strings.NewReader("a,b,c\n"))
+	require.Error(t, err)
+}
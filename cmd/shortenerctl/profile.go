@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultProfile = "default"
+	defaultServer  = "http://localhost:8080"
+)
+
+// Profile - настройки подключения к серверу.
+type Profile struct {
+	Server     string `json:"server"`
+	APIKey     string `json:"api_key,omitempty"`
+	AdminToken string `json:"admin_token,omitempty"` // токен администратора для stats
+	Cookie     string `json:"cookie,omitempty"`      // cookie пользователя, выданная сервером
+}
+
+// Profiles - файл профилей.
+type Profiles struct {
+	Current  string              `json:"current"`
+	Profiles map[string]*Profile `json:"profiles"`
+}
+
+// defaultConfigPath - путь к файлу профилей по умолчанию.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".shortenerctl.json"
+	}
+	return filepath.Join(dir, "shortenerctl", "config.json")
+}
+
+// loadProfiles - чтение файла профилей. Отсутствующий файл - пустой набор профилей.
+func loadProfiles(path string) (*Profiles, error) {
+	p := &Profiles{Current: defaultProfile, Profiles: map[string]*Profile{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	if p.Profiles == nil {
+		p.Profiles = map[string]*Profile{}
+	}
+	if p.Current == "" {
+		p.Current = defaultProfile
+	}
+	return p, nil
+}
+
+// save - запись файла профилей. Файл содержит учётные данные, поэтому доступен только владельцу.
+func (p *Profiles) save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// get - профиль по имени, при отсутствии создаётся профиль по умолчанию.
+func (p *Profiles) get(name string) *Profile {
+	if name == "" {
+		name = p.Current
+	}
+	profile, ok := p.Profiles[name]
+	if !ok {
+		profile = &Profile{Server: defaultServer}
+		p.Profiles[name] = profile
+	}
+	return profile
+}
@@ -0,0 +1,176 @@
+// Package accesslog - журнал запросов в формате веб-сервера, отдельный от логов приложения.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+)
+
+// Форматы журнала.
+const (
+	FormatCombined = "combined" // Apache Combined Log Format
+	FormatJSON     = "json"     // одна JSON-запись на строку
+)
+
+// Формат времени Combined Log Format.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// Entry - запись журнала о запросе.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	RemoteIP  string    `json:"remote_ip"`
+	Method    string    `json:"method"`
+	URI       string    `json:"uri"`
+	Proto     string    `json:"proto"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	Duration  float64   `json:"duration_ms"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// Logger - журнал запросов.
+type Logger struct {
+	format string
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// New - журнал в формате format, пишущий в out.
+func New(out io.Writer, format string) (*Logger, error) {
+	if format == "" {
+		format = FormatCombined
+	}
+	if format != FormatCombined && format != FormatJSON {
+		return nil, fmt.Errorf("unknown access log format %q", format)
+	}
+	return &Logger{format: format, out: out}, nil
+}
+
+// Log - запись одной строки журнала.
+func (l *Logger) Log(e Entry) error {
+	var line []byte
+	if l.format == FormatJSON {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		line = append(data, '\n')
+	} else {
+		line = []byte(combined(e))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.out.Write(line)
+	return err
+}
+
+// responseWriter - ResponseWriter, считающий статус и отправленные байты.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(data []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(data)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Middleware - запись каждого запроса в журнал после ответа.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(rw, r)
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		err := l.Log(Entry{
+			Time:      start,
+			RemoteIP:  clientip.FromRequest(r),
+			Method:    r.Method,
+			URI:       r.RequestURI,
+			Proto:     r.Proto,
+			Status:    rw.status,
+			Bytes:     rw.bytes,
+			Duration:  float64(time.Since(start).Microseconds()) / 1000,
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+			RequestID: logger.RequestID(r.Context()),
+		})
+		if err != nil {
+			logger.FromContext(r.Context()).Errorw("access log write failed", "error", err)
+		}
+	})
+}
+
+// combined - строка в Apache Combined Log Format.
+func combined(e Entry) string {
+	size := "-"
+	if e.Bytes > 0 {
+		size = strconv.FormatInt(e.Bytes, 10)
+	}
+	return fmt.Sprintf("%s - - [%s] %s %d %s %s %s\n",
+		dash(e.RemoteIP),
+		e.Time.Format(clfTimeFormat),
+		quote(e.Method+" "+e.URI+" "+e.Proto),
+		e.Status,
+		size,
+		quote(dash(e.Referer)),
+		quote(dash(e.UserAgent)),
+	)
+}
+
+// quote - строка в кавычках с экранированием кавычек и управляющих символов.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range []byte(s) {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// dash - "-" вместо пустого значения.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombined(t *testing.T) {
+	e := Entry{
+		Time:      time.Date(2026, 3, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		RemoteIP:  "127.0.0.1",
+		Method:    "GET",
+		URI:       "/abc",
+		Proto:     "HTTP/1.1",
+		Status:    307,
+		Bytes:     0,
+		Referer:   "http://example.com/start",
+		UserAgent: `Mozilla/4.08 "quoted"`,
+	}
+	require.Equal(t,
+		`127.0.0.1 - - [10/Mar/2026:13:55:36 -0700] "GET /abc HTTP/1.1" 307 - "http://example.com/start" "Mozilla/4.08 \"quoted\""`+"\n",
+		combined(e))
+
+	e.Bytes, e.Referer, e.UserAgent = 2326, "", "curl\n"
+	require.Contains(t, combined(e), `307 2326 "-" "curl\x0a"`)
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("http://localhost/"))
+		w.Write([]byte("abc"))
+	})
+
+	t.Run("combined", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := New(&buf, FormatCombined)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/", nil)
+		req.RemoteAddr = "10.0.0.1:5555"
+		req.Header.Set("User-Agent", "test")
+		l.Middleware(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Regexp(t, `^10\.0\.0\.1 - - \[.+\] "POST / HTTP/1\.1" 201 20 "-" "test"\n$`, buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := New(&buf, FormatJSON)
+		require.NoError(t, err)
+
+		l.Middleware(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+
+		var e Entry
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+		require.Equal(t, http.StatusCreated, e.Status)
+		require.Equal(t, int64(20), e.Bytes)
+		require.Equal(t, "POST", e.Method)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, "common")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,190 @@
+package accesslog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Формат отметки времени в имени ротированного файла.
+const rotateTimeFormat = "20060102T150405.000"
+
+// Rotator - файл с ротацией по размеру и времени. Старые файлы сжимаются gzip,
+// хранится не больше keep сжатых копий.
+type Rotator struct {
+	path     string
+	maxSize  int64         // 0 - без ротации по размеру
+	interval time.Duration // 0 - без ротации по времени
+	keep     int           // 0 - без ограничения
+	now      func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	wg sync.WaitGroup // фоновое сжатие
+}
+
+// OpenRotator - открытие файла path на дозапись.
+func OpenRotator(path string, maxSize int64, interval time.Duration, keep int) (*Rotator, error) {
+	r := &Rotator{
+		path:     path,
+		maxSize:  maxSize,
+		interval: interval,
+		keep:     keep,
+		now:      time.Now,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write - запись с ротацией перед записью, если файл переполнен или устарел.
+func (r *Rotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.due(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate - принудительная ротация.
+func (r *Rotator) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.rotate()
+}
+
+// Close - закрытие файла с ожиданием фонового сжатия.
+func (r *Rotator) Close() error {
+	r.mu.Lock()
+	err := r.file.Close()
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return err
+}
+
+// due - нужна ли ротация перед записью n байт.
+func (r *Rotator) due(n int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.maxSize > 0 && r.size+n > r.maxSize {
+		return true
+	}
+	return r.interval > 0 && r.now().Sub(r.opened) >= r.interval
+}
+
+// open - открытие текущего файла.
+func (r *Rotator) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.opened = r.now()
+	return nil
+}
+
+// rotate - переименование текущего файла, открытие нового и сжатие старого в фоне.
+// Старый файл закрывается только после открытия нового, поэтому при ошибке запись продолжается в него.
+func (r *Rotator) rotate() error {
+	ext := filepath.Ext(r.path)
+	rotated := strings.TrimSuffix(r.path, ext) + "-" + r.now().UTC().Format(rotateTimeFormat) + ext
+	if err := os.Rename(r.path, rotated); err != nil {
+		return err
+	}
+
+	old := r.file
+	if err := r.open(); err != nil {
+		os.Rename(rotated, r.path) // возвращаем прежнее имя открытому файлу
+		return err
+	}
+	old.Close() // запись в файл не буферизуется, данные уже у ОС
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if err := compress(rotated); err == nil {
+			r.prune()
+		}
+	}()
+	return nil
+}
+
+// prune - удаление сжатых копий сверх keep, начиная с самых старых.
+func (r *Rotator) prune() {
+	if r.keep <= 0 {
+		return
+	}
+
+	ext := filepath.Ext(r.path)
+	matches, err := filepath.Glob(strings.TrimSuffix(r.path, ext) + "-*" + ext + ".gz")
+	if err != nil || len(matches) <= r.keep {
+		return
+	}
+
+	// Отметка времени в имени сортируется лексикографически
+	sort.Strings(matches)
+	for _, name := range matches[:len(matches)-r.keep] {
+		os.Remove(name)
+	}
+}
+
+// compress - сжатие файла name в name.gz с удалением исходного.
+func compress(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(name + ".gz")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+
+	return os.Remove(name)
+}
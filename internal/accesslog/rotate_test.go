@@ -0,0 +1,111 @@
+package accesslog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotator(t *testing.T) {
+	t.Run("rotate by size", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "access.log")
+
+		r, err := OpenRotator(path, 10, 0, 0)
+		require.NoError(t, err)
+		clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		r.now = func() time.Time { return clock }
+
+		_, err = r.Write([]byte("line one\n"))
+		require.NoError(t, err)
+		_, err = r.Write([]byte("line two\n"))
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "line two\n", string(data))
+
+		gz, err := os.Open(filepath.Join(dir, "access-20260101T000000.000.log.gz"))
+		require.NoError(t, err)
+		defer gz.Close()
+		zr, err := gzip.NewReader(gz)
+		require.NoError(t, err)
+		data, err = io.ReadAll(zr)
+		require.NoError(t, err)
+		require.Equal(t, "line one\n", string(data))
+	})
+
+	t.Run("rotate by time and keep", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "access.log")
+
+		r, err := OpenRotator(path, 0, time.Hour, 2)
+		require.NoError(t, err)
+		clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		r.now = func() time.Time { return clock }
+		r.opened = clock
+
+		for i := 0; i < 4; i++ {
+			_, err = r.Write([]byte("line\n"))
+			require.NoError(t, err)
+			clock = clock.Add(time.Hour)
+			r.wg.Wait() // сжатие и удаление идут в фоне
+		}
+		require.NoError(t, r.Close())
+
+		matches, err := filepath.Glob(filepath.Join(dir, "access-*.log.gz"))
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			filepath.Join(dir, "access-20260101T020000.000.log.gz"),
+			filepath.Join(dir, "access-20260101T030000.000.log.gz"),
+		}, matches)
+	})
+
+	t.Run("append to existing", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "access.log")
+		require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+
+		r, err := OpenRotator(path, 1<<20, 0, 0)
+		require.NoError(t, err)
+		r.Write([]byte("new\n"))
+		require.NoError(t, r.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "old\nnew\n", string(data))
+	})
+
+	t.Run("failed rotation keeps file open", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "access.log")
+
+		r, err := OpenRotator(path, 10, 0, 0)
+		require.NoError(t, err)
+		clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		r.now = func() time.Time { return clock }
+
+		// Непустая директория на месте ротированного файла не даёт переименовать текущий
+		rotated := filepath.Join(dir, "access-20260101T000000.000.log")
+		require.NoError(t, os.MkdirAll(filepath.Join(rotated, "busy"), 0o755))
+
+		_, err = r.Write([]byte("line one\n"))
+		require.NoError(t, err)
+		_, err = r.Write([]byte("line two\n"))
+		require.Error(t, err)
+
+		require.NoError(t, os.RemoveAll(rotated))
+		_, err = r.Write([]byte("line three\n"))
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "line three\n", string(data))
+	})
+}
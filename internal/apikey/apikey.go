@@ -0,0 +1,381 @@
+// Package apikey - API-ключи сервисных клиентов с квотами на создание ссылок.
+// Ключи хранятся в JSON-файле только в виде хеша, сам ключ показывается один раз при создании.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/utils"
+)
+
+var (
+	ErrNotFound = errors.New("API key not found")
+	ErrInvalid  = errors.New("invalid API key")
+)
+
+// Длина идентификатора ключа.
+const idLength = 8
+
+// Формат дня для дневной квоты, дни считаются по UTC.
+const dayFormat = "2006-01-02"
+
+// Key - API-ключ без секретной части.
+type Key struct {
+	ID         string     `json:"id"`
+	Hash       string     `json:"hash"` // sha256 от ключа
+	Owner      string     `json:"owner"`
+	Name       string     `json:"name,omitempty"`
+	DailyQuota int        `json:"daily_quota"` // ссылок в сутки, 0 - без ограничения
+	TotalQuota int        `json:"total_quota"` // ссылок всего, 0 - без ограничения
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Usage      Usage      `json:"usage"`
+}
+
+// Usage - расход квот ключа.
+type Usage struct {
+	Day   string `json:"day"`   // день, к которому относится Daily
+	Daily int    `json:"daily"` // создано за день
+	Total int    `json:"total"` // создано всего
+}
+
+// Quota - лимиты и остаток квот ключа. Remaining равен -1 для квоты без ограничения.
+type Quota struct {
+	DailyLimit     int
+	DailyRemaining int
+	TotalLimit     int
+	TotalRemaining int
+	Reset          time.Time // начало следующих суток
+}
+
+// Store - API-ключи с сохранением в файл при каждом изменении.
+// Файл могут менять и другие процессы, например shortener-admin при работающем сервере,
+// поэтому перед каждой операцией изменённый файл перечитывается.
+type Store struct {
+	path string
+	now  func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]*Key
+	modTime time.Time // время изменения файла при последнем чтении или записи
+	size    int64
+}
+
+// Open - загрузка ключей из файла path. Отсутствующий файл - пустой набор ключей.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, now: time.Now, keys: make(map[string]*Key)}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload - чтение файла, если он изменился после последнего чтения или записи, вызывается под блокировкой.
+// Отсутствующий файл не сбрасывает ключи в памяти.
+func (s *Store) reload() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+
+	s.keys = make(map[string]*Key, len(keys))
+	for i := range keys {
+		s.keys[keys[i].ID] = &keys[i]
+	}
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// Create - создание ключа владельца owner. Возвращает ключ, который больше нигде не хранится.
+// При пустом owner владельцем становится сам ключ.
+func (s *Store) Create(owner, name string, dailyQuota, totalQuota int) (string, Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", Key{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return "", Key{}, err
+	}
+
+	id := utils.GenerateShortURL(idLength)
+	for s.keys[id] != nil {
+		id = utils.GenerateShortURL(idLength)
+	}
+	if owner == "" {
+		owner = "key-" + id
+	}
+
+	token := id + "." + base64.RawURLEncoding.EncodeToString(secret)
+	key := &Key{
+		ID:         id,
+		Hash:       hash(token),
+		Owner:      owner,
+		Name:       name,
+		DailyQuota: dailyQuota,
+		TotalQuota: totalQuota,
+		CreatedAt:  s.now().UTC(),
+	}
+	s.keys[id] = key
+
+	if err := s.save(); err != nil {
+		delete(s.keys, id)
+		return "", Key{}, err
+	}
+	return token, *key, nil
+}
+
+// List - все ключи в порядке создания.
+func (s *Store) List() []Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reload() // при ошибке чтения - ключи в памяти
+	return s.list()
+}
+
+// Revoke - отзыв ключа.
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+
+	now := s.now().UTC()
+	key.RevokedAt = &now
+	if err := s.save(); err != nil {
+		key.RevokedAt = nil
+		return err
+	}
+	return nil
+}
+
+// Authenticate - действующий ключ по его значению.
+func (s *Store) Authenticate(token string) (Key, error) {
+	id, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return Key{}, ErrInvalid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reload()
+
+	key, ok := s.keys[id]
+	if !ok || key.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash(token))) != 1 {
+		return Key{}, ErrInvalid
+	}
+	return *key, nil
+}
+
+// Quota - остаток квот ключа.
+func (s *Store) Quota(id string) (Quota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reload()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return Quota{}, ErrNotFound
+	}
+	return s.quota(key), nil
+}
+
+// Reserve - списание n ссылок с квот ключа из контекста. false - квота исчерпана.
+// Запросы без ключа не ограничиваются.
+func (s *Store) Reserve(ctx context.Context, n int) (bool, error) {
+	k, ok := FromContext(ctx)
+	if !ok {
+		return true, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return false, err
+	}
+
+	key, ok := s.keys[k.ID]
+	if !ok {
+		return false, ErrNotFound
+	}
+
+	q := s.quota(key)
+	if (q.DailyRemaining >= 0 && q.DailyRemaining < n) || (q.TotalRemaining >= 0 && q.TotalRemaining < n) {
+		return false, nil
+	}
+
+	prev := key.Usage
+	key.Usage.Daily = s.usedToday(key) + n
+	key.Usage.Day = s.today()
+	key.Usage.Total += n
+
+	if err := s.save(); err != nil {
+		key.Usage = prev
+		return false, err
+	}
+	return true, nil
+}
+
+// Release - возврат n ссылок, которые не были созданы после Reserve.
+func (s *Store) Release(ctx context.Context, n int) {
+	k, ok := FromContext(ctx)
+	if !ok || n <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reload() != nil {
+		return
+	}
+
+	key, ok := s.keys[k.ID]
+	if !ok {
+		return
+	}
+	if key.Usage.Day == s.today() {
+		key.Usage.Daily = max(0, key.Usage.Daily-n)
+	}
+	key.Usage.Total = max(0, key.Usage.Total-n)
+	s.save()
+}
+
+// quota - остаток квот ключа на текущий момент.
+func (s *Store) quota(key *Key) Quota {
+	now := s.now().UTC()
+	q := Quota{
+		DailyLimit:     key.DailyQuota,
+		DailyRemaining: -1,
+		TotalLimit:     key.TotalQuota,
+		TotalRemaining: -1,
+		Reset:          time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+	}
+	if key.DailyQuota > 0 {
+		q.DailyRemaining = max(0, key.DailyQuota-s.usedToday(key))
+	}
+	if key.TotalQuota > 0 {
+		q.TotalRemaining = max(0, key.TotalQuota-key.Usage.Total)
+	}
+	return q
+}
+
+// usedToday - создано ссылок за текущие сутки.
+func (s *Store) usedToday(key *Key) int {
+	if key.Usage.Day != s.today() {
+		return 0
+	}
+	return key.Usage.Daily
+}
+
+// today - текущие сутки UTC.
+func (s *Store) today() string {
+	return s.now().UTC().Format(dayFormat)
+}
+
+// list - ключи по времени создания, вызывается под блокировкой.
+func (s *Store) list() []Key {
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// save - запись ключей через временный файл, вызывается под блокировкой.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	// Своя запись не должна вызывать повторное чтение
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+// hash - хеш значения ключа. Ключ случайный и длинный, поэтому медленный KDF не нужен.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type ctxKey struct{}
+
+// WithKey - контекст с ключом, которым аутентифицирован запрос.
+func WithKey(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, ctxKey{}, key)
+}
+
+// FromContext - ключ запроса из контекста.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(ctxKey{}).(Key)
+	return key, ok
+}
@@ -0,0 +1,161 @@
+package apikey
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T) (*Store, string, *time.Time) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	s, err := Open(path)
+	require.NoError(t, err)
+
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+	return s, path, &clock
+}
+
+func TestStore(t *testing.T) {
+	t.Run("create and authenticate", func(t *testing.T) {
+		s, path, _ := newStore(t)
+
+		token, key, err := s.Create("owner1", "job", 10, 100)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(token, key.ID+"."))
+
+		got, err := s.Authenticate(token)
+		require.NoError(t, err)
+		require.Equal(t, "owner1", got.Owner)
+
+		_, err = s.Authenticate(key.ID + ".wrong")
+		require.ErrorIs(t, err, ErrInvalid)
+		_, err = s.Authenticate("garbage")
+		require.ErrorIs(t, err, ErrInvalid)
+
+		// В файле только хеш
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), token)
+		require.Contains(t, string(data), key.Hash)
+	})
+
+	t.Run("default owner", func(t *testing.T) {
+		s, _, _ := newStore(t)
+		_, key, err := s.Create("", "", 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, "key-"+key.ID, key.Owner)
+	})
+
+	t.Run("revoke and reopen", func(t *testing.T) {
+		s, path, _ := newStore(t)
+		token, key, err := s.Create("owner1", "", 0, 0)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Revoke(key.ID))
+		require.ErrorIs(t, s.Revoke("missing"), ErrNotFound)
+		_, err = s.Authenticate(token)
+		require.ErrorIs(t, err, ErrInvalid)
+
+		reopened, err := Open(path)
+		require.NoError(t, err)
+		keys := reopened.List()
+		require.Len(t, keys, 1)
+		require.NotNil(t, keys[0].RevokedAt)
+	})
+
+	t.Run("changes from another process", func(t *testing.T) {
+		server, path, _ := newStore(t)
+		tokenA, keyA, err := server.Create("owner1", "", 0, 0)
+		require.NoError(t, err)
+
+		// shortener-admin при работающем сервере
+		cli, err := Open(path)
+		require.NoError(t, err)
+		tokenB, keyB, err := cli.Create("owner2", "", 0, 0)
+		require.NoError(t, err)
+		require.NoError(t, cli.Revoke(keyA.ID))
+
+		_, err = server.Authenticate(tokenA)
+		require.ErrorIs(t, err, ErrInvalid)
+		_, err = server.Authenticate(tokenB)
+		require.NoError(t, err)
+
+		ok, err := server.Reserve(WithKey(context.Background(), keyB), 1)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		reopened, err := Open(path)
+		require.NoError(t, err)
+		keys := reopened.List()
+		require.Len(t, keys, 2)
+		byID := map[string]Key{keys[0].ID: keys[0], keys[1].ID: keys[1]}
+		require.NotNil(t, byID[keyA.ID].RevokedAt)
+		require.Equal(t, 1, byID[keyB.ID].Usage.Total)
+	})
+}
+
+func TestQuota(t *testing.T) {
+	s, path, clock := newStore(t)
+	_, key, err := s.Create("owner1", "", 2, 3)
+	require.NoError(t, err)
+	ctx := WithKey(context.Background(), key)
+
+	ok, err := s.Reserve(ctx, 3)
+	require.NoError(t, err)
+	require.False(t, ok, "больше дневной квоты")
+
+	ok, err = s.Reserve(ctx, 2)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	q, err := s.Quota(key.ID)
+	require.NoError(t, err)
+	require.Equal(t, Quota{
+		DailyLimit:     2,
+		DailyRemaining: 0,
+		TotalLimit:     3,
+		TotalRemaining: 1,
+		Reset:          time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+	}, q)
+
+	// Возврат несозданных ссылок
+	s.Release(ctx, 1)
+	q, _ = s.Quota(key.ID)
+	require.Equal(t, 1, q.DailyRemaining)
+	require.Equal(t, 2, q.TotalRemaining)
+
+	// Новые сутки обнуляют дневную квоту, но не общую
+	*clock = clock.Add(24 * time.Hour)
+	ok, err = s.Reserve(ctx, 2)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = s.Reserve(ctx, 1)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Расход сохраняется между перезапусками
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	reopened.now = s.now
+	q, _ = reopened.Quota(key.ID)
+	require.Equal(t, 0, q.TotalRemaining)
+
+	// Запросы без ключа не ограничены
+	ok, err = s.Reserve(context.Background(), 1000)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Неограниченная квота
+	_, unlimited, err := s.Create("owner2", "", 0, 0)
+	require.NoError(t, err)
+	q, _ = s.Quota(unlimited.ID)
+	require.Equal(t, -1, q.DailyRemaining)
+	require.Equal(t, -1, q.TotalRemaining)
+}
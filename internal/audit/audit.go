@@ -0,0 +1,218 @@
+// Package audit - журнал действий со ссылками и ключами.
+// События только дописываются в конец JSONL-файла и никогда не изменяются.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// Действия, записываемые в журнал.
+const (
+	ActionLinkCreate   = "link.create"
+	ActionLinkUpdate   = "link.update"
+	ActionLinkDisable  = "link.disable"
+	ActionLinkEnable   = "link.enable"
+	ActionLinkReassign = "link.reassign"
+	ActionLinkDelete   = "link.delete"
+	ActionAPIKeyCreate = "apikey.create"
+	ActionAPIKeyRevoke = "apikey.revoke"
+)
+
+// ActorAdmin - исполнитель действий административного API.
+const ActorAdmin = "admin"
+
+// Link - состояние ссылки до или после действия.
+type Link struct {
+	ShortURL     string            `json:"short_url"`
+	OriginalURL  string            `json:"original_url"`
+	UserID       string            `json:"user_id,omitempty"`
+	Deleted      bool              `json:"deleted,omitempty"`
+	Disabled     bool              `json:"disabled,omitempty"`
+	RedirectCode int               `json:"redirect_code,omitempty"`
+	ExpiresAt    time.Time         `json:"expires_at,omitzero"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Version      int               `json:"version,omitempty"`
+	Title        string            `json:"title,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	Notes        string            `json:"notes,omitempty"`
+	Protected    bool              `json:"protected,omitempty"` // защищена паролем, сам хеш в журнал не пишется
+	MaxClicks    int64             `json:"max_clicks,omitempty"`
+	NotBefore    time.Time         `json:"not_before,omitzero"`
+}
+
+// Event - событие журнала.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	IP     string    `json:"ip,omitempty"`
+	Action string    `json:"action"`
+	Key    string    `json:"key,omitempty"`
+	Before *Link     `json:"before,omitempty"`
+	After  *Link     `json:"after,omitempty"`
+}
+
+// Snapshot - состояние записи хранилища для события.
+func Snapshot(rec storage.Record) *Link {
+	return &Link{
+		ShortURL:     rec.ShortURL,
+		OriginalURL:  rec.OriginalURL,
+		UserID:       rec.UserID,
+		Deleted:      rec.DeletedFlag,
+		Disabled:     rec.Disabled,
+		RedirectCode: rec.RedirectCode,
+		ExpiresAt:    rec.ExpiresAt,
+		Metadata:     rec.Metadata,
+		Version:      rec.Version,
+		Title:        rec.Title,
+		Tags:         rec.Tags,
+		Notes:        rec.Notes,
+		Protected:    rec.PasswordHash != "",
+		MaxClicks:    rec.MaxClicks,
+		NotBefore:    rec.NotBefore,
+	}
+}
+
+// Filter - условия выборки событий. Пустые условия не ограничивают выборку.
+type Filter struct {
+	From   time.Time // не раньше
+	To     time.Time // раньше
+	Actor  string
+	Action string
+	Key    string
+}
+
+// Match - соответствие события условиям.
+func (f Filter) Match(ev Event) bool {
+	return (f.From.IsZero() || !ev.Time.Before(f.From)) &&
+		(f.To.IsZero() || ev.Time.Before(f.To)) &&
+		(f.Actor == "" || ev.Actor == f.Actor) &&
+		(f.Action == "" || ev.Action == f.Action) &&
+		(f.Key == "" || ev.Key == f.Key)
+}
+
+// Log - журнал в файле. Нулевой *Log ничего не записывает.
+type Log struct {
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64 // размер записанной части файла
+}
+
+// Open - открытие журнала в файле path на дозапись.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Log{path: path, now: time.Now, file: file, size: info.Size()}, nil
+}
+
+// Record - запись события в конец журнала. Время проставляется, если не задано,
+// под блокировкой, поэтому события в файле упорядочены по времени.
+// Событие сбрасывается на диск до возврата.
+func (l *Log) Record(ev Event) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ev.Time.IsZero() {
+		ev.Time = l.now().UTC()
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Report - запись события о выполненном действии. Действие уже не отменить,
+// поэтому сбой журнала только логируется.
+func (l *Log) Report(ctx context.Context, ev Event) {
+	if err := l.Record(ev); err != nil {
+		logger.FromContext(ctx).Errorw("audit record failed", "action", ev.Action, "key", ev.Key, "error", err)
+	}
+}
+
+// Query - обход событий, подходящих под f, в порядке записи.
+// Читается только часть файла, записанная к началу обхода, поэтому запись не блокируется.
+// Обход прекращается при ошибке fn или после события позже f.To.
+func (l *Log) Query(f Filter, fn func(Event) error) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	size := l.size
+	l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(io.LimitReader(file, size))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return fmt.Errorf("%s:%d: %w", l.path, n, err)
+		}
+		if !f.To.IsZero() && !ev.Time.Before(f.To) {
+			return nil
+		}
+		if !f.Match(ev) {
+			continue
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Close - закрытие файла журнала.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+
+	l, err := Open(path)
+	require.NoError(t, err)
+	l.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	before := storage.Record{ShortURL: "key", OriginalURL: "https://example.com", UserID: "user1"}
+	after := before
+	after.Disabled = true
+	require.NoError(t, l.Record(Event{Actor: ActorAdmin, IP: "10.0.0.1", Action: ActionLinkDisable, Key: "key",
+		Before: Snapshot(before), After: Snapshot(after)}))
+	require.NoError(t, l.Close())
+
+	// Повторное открытие дописывает, а не перезаписывает файл
+	l, err = Open(path)
+	require.NoError(t, err)
+	require.NoError(t, l.Record(Event{Actor: ActorAdmin, Action: ActionAPIKeyRevoke, Key: "id"}))
+	require.NoError(t, l.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var ev Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+		events = append(events, ev)
+	}
+	require.Len(t, events, 2)
+	require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), events[0].Time)
+	require.Equal(t, "10.0.0.1", events[0].IP)
+	require.False(t, events[0].Before.Disabled)
+	require.True(t, events[0].After.Disabled)
+	require.Equal(t, ActionAPIKeyRevoke, events[1].Action)
+	require.False(t, events[1].Time.IsZero())
+
+	var nilLog *Log
+	require.NoError(t, nilLog.Record(Event{}))
+	require.NoError(t, nilLog.Close())
+}
+
+func TestQuery(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	defer l.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	l.now = func() time.Time {
+		clock = clock.Add(time.Hour)
+		return clock
+	}
+
+	require.NoError(t, l.Record(Event{Actor: "user1", Action: ActionLinkCreate, Key: "a"}))     // 01:00
+	require.NoError(t, l.Record(Event{Actor: "user2", Action: ActionLinkCreate, Key: "b"}))     // 02:00
+	require.NoError(t, l.Record(Event{Actor: "user1", Action: ActionLinkDelete, Key: "a"}))     // 03:00
+	require.NoError(t, l.Record(Event{Actor: ActorAdmin, Action: ActionLinkDisable, Key: "b"})) // 04:00
+
+	query := func(f Filter) []string {
+		var keys []string
+		require.NoError(t, l.Query(f, func(ev Event) error {
+			keys = append(keys, ev.Actor+":"+ev.Action+":"+ev.Key)
+			return nil
+		}))
+		return keys
+	}
+
+	require.Len(t, query(Filter{}), 4)
+	require.Equal(t, []string{"user1:link.create:a", "user1:link.delete:a"}, query(Filter{Actor: "user1"}))
+	require.Equal(t, []string{"user2:link.create:b", "user1:link.delete:a"},
+		query(Filter{From: start.Add(2 * time.Hour), To: start.Add(4 * time.Hour)}))
+	require.Equal(t, []string{"admin:link.disable:b"}, query(Filter{Key: "b", Action: ActionLinkDisable}))
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = l.Query(Filter{}, func(Event) error {
+		calls++
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	require.Equal(t, 1, calls)
+
+	var nilLog *Log
+	require.NoError(t, nilLog.Query(Filter{}, func(Event) error { return errStop }))
+}
+
+func TestReport(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	ctx := logger.WithContext(context.Background(), zap.New(core).Sugar())
+
+	l, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	l.Report(ctx, Event{Actor: "user1", Action: ActionLinkCreate, Key: "a"})
+	require.Zero(t, logs.Len())
+
+	// Сбой записи не возвращается вызывающему, а попадает в лог
+	require.NoError(t, l.Close())
+	l.Report(ctx, Event{Actor: "user1", Action: ActionLinkDelete, Key: "a"})
+	require.Equal(t, 1, logs.Len())
+	require.Equal(t, ActionLinkDelete, logs.All()[0].ContextMap()["action"])
+
+	var nilLog *Log
+	nilLog.Report(ctx, Event{Action: ActionLinkCreate})
+	require.Equal(t, 1, logs.Len())
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/utils"
+)
+
+// CookieName - имя cookie с идентификатором пользователя.
+const CookieName = "user_id"
+
+// Длина идентификатора пользователя.
+const userIDLength = 16
+
+type contextKey struct{}
+
+// Auth - выдача и проверка подписанных cookie пользователя и API-ключей.
+type Auth struct {
+	secret []byte
+	keys   *apikey.Store // nil - API-ключи не принимаются
+}
+
+// New - создание аутентификатора. При пустом секрете генерируется случайный.
+func New(secret string) *Auth {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("failed to generate secret: " + err.Error())
+		}
+	}
+	return &Auth{secret: key}
+}
+
+// LoadSecret - секрет подписи cookie из файла path. Если файла нет, создаётся новый
+// случайный секрет с правами 0600, чтобы cookie оставались действительны после перезапуска.
+func LoadSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		secret := strings.TrimSpace(string(data))
+		if secret == "" {
+			return "", fmt.Errorf("secret file %s is empty", path)
+		}
+		return secret, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	// O_EXCL защищает от гонки двух процессов, создающих файл одновременно
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return LoadSecret(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(secret + "\n"); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return secret, nil
+}
+
+// WithAPIKeys - приём API-ключей из keys в заголовке Authorization: Bearer.
+func (a *Auth) WithAPIKeys(keys *apikey.Store) *Auth {
+	a.keys = keys
+	return a
+}
+
+// Middleware - определяет пользователя по API-ключу или cookie, при отсутствии выдаёт новую cookie.
+// Запрос с недействительным API-ключом отклоняется с 401.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := BearerToken(r); ok && a.keys != nil {
+			key, err := a.keys.Authenticate(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := apikey.WithKey(WithUserID(r.Context(), key.Owner), key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		userID, ok := a.userFromRequest(r)
+		if !ok {
+			userID = utils.GenerateShortURL(userIDLength)
+			http.SetCookie(w, &http.Cookie{
+				Name:     CookieName,
+				Value:    a.sign(userID),
+				Path:     "/",
+				HttpOnly: true,
+			})
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), userID)))
+	})
+}
+
+// Admin - доступ только с токеном администратора в Authorization: Bearer.
+// При пустом токене административный API отключён.
+func Admin(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				http.NotFound(w, r)
+				return
+			}
+			got, ok := BearerToken(r)
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BearerToken - токен из заголовка Authorization: Bearer. Схема сравнивается без учёта регистра.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// WithUserID - контекст с идентификатором пользователя.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+// UserID - идентификатор пользователя из контекста.
+func UserID(ctx context.Context) string {
+	userID, _ := ctx.Value(contextKey{}).(string)
+	return userID
+}
+
+// userFromRequest - проверка подписи cookie.
+func (a *Auth) userFromRequest(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return "", false
+	}
+
+	userID, _, found := strings.Cut(cookie.Value, ".")
+	if !found || userID == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(cookie.Value), []byte(a.sign(userID))) {
+		return "", false
+	}
+	return userID, true
+}
+
+// sign - значение cookie в формате "<id>.<hmac>".
+func (a *Auth) sign(userID string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(userID))
+	return userID + "." + hex.EncodeToString(mac.Sum(nil))
+}
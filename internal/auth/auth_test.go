@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	a := New("secret")
+
+	var got string
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = UserID(r.Context())
+	}))
+
+	t.Run("issues cookie", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		cookies := rec.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.Equal(t, CookieName, cookies[0].Name)
+		require.Len(t, got, userIDLength)
+	})
+
+	t.Run("accepts signed cookie", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: CookieName, Value: a.sign("user1")})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Empty(t, rec.Result().Cookies())
+		require.Equal(t, "user1", got)
+	})
+
+	t.Run("rejects forged cookie", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: CookieName, Value: New("other").sign("user1")})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Len(t, rec.Result().Cookies(), 1)
+		require.NotEqual(t, "user1", got)
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	keys, err := apikey.Open(filepath.Join(t.TempDir(), "apikeys.json"))
+	require.NoError(t, err)
+	token, key, err := keys.Create("service", "", 0, 0)
+	require.NoError(t, err)
+
+	a := New("secret").WithAPIKeys(keys)
+
+	var got string
+	var gotKey apikey.Key
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = UserID(r.Context())
+		gotKey, _ = apikey.FromContext(r.Context())
+	}))
+
+	t.Run("accepts valid key", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Empty(t, rec.Result().Cookies())
+		require.Equal(t, "service", got)
+		require.Equal(t, key.ID, gotKey.ID)
+	})
+
+	t.Run("rejects unknown key", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+key.ID+".forged")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("rejects revoked key", func(t *testing.T) {
+		require.NoError(t, keys.Revoke(key.ID))
+
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestAdmin(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{name: "disabled", token: "", header: "Bearer ", want: http.StatusNotFound},
+		{name: "no token", token: "admin", header: "", want: http.StatusUnauthorized},
+		{name: "wrong token", token: "admin", header: "Bearer other", want: http.StatusUnauthorized},
+		{name: "valid token", token: "admin", header: "Bearer admin", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/admin/keys", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			Admin(tt.token)(ok).ServeHTTP(rec, req)
+			require.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestLoadSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "db.json.secret")
+
+	secret, err := LoadSecret(path)
+	require.NoError(t, err)
+	require.Len(t, secret, 64)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// После перезапуска cookie, подписанная прежним секретом, остаётся действительной
+	again, err := LoadSecret(path)
+	require.NoError(t, err)
+	require.Equal(t, secret, again)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: New(secret).sign("user-1")})
+	userID, ok := New(again).userFromRequest(req)
+	require.True(t, ok)
+	require.Equal(t, "user-1", userID)
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+	_, err = LoadSecret(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeFormat - метка времени в имени резервной копии.
+const timeFormat = "20060102T150405.000Z"
+
+// Snapshotter - источник данных для резервной копии.
+type Snapshotter interface {
+	SaveToFile(filePath string) error
+}
+
+// Manager - создание и ротация резервных копий файла хранилища.
+type Manager struct {
+	db     Snapshotter
+	dir    string
+	prefix string
+	ext    string
+	keep   int
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// New - менеджер копий файла storagePath в каталоге dir.
+// keep - сколько последних копий хранить, maxAge - максимальный возраст копии; 0 - без ограничения.
+func New(db Snapshotter, storagePath, dir string, keep int, maxAge time.Duration) *Manager {
+	base := filepath.Base(storagePath)
+	ext := filepath.Ext(base)
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(storagePath), "backups")
+	}
+
+	return &Manager{
+		db:     db,
+		dir:    dir,
+		prefix: strings.TrimSuffix(base, ext) + "-",
+		ext:    ext,
+		keep:   keep,
+		maxAge: maxAge,
+		now:    time.Now,
+	}
+}
+
+// Snapshot - создание резервной копии и удаление устаревших. Возвращает путь к копии.
+func (m *Manager) Snapshot() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := filepath.Join(m.dir, m.prefix+m.now().UTC().Format(timeFormat)+m.ext)
+	if err := m.db.SaveToFile(path); err != nil {
+		return "", fmt.Errorf("backup: %w", err)
+	}
+
+	if err := m.rotate(); err != nil {
+		return path, fmt.Errorf("backup rotation: %w", err)
+	}
+	return path, nil
+}
+
+// List - существующие копии, от новых к старым.
+func (m *Manager) List() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	backups, err := m.list()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, len(backups))
+	for i, b := range backups {
+		paths[i] = b.path
+	}
+	return paths, nil
+}
+
+// backup - найденная резервная копия.
+type backup struct {
+	path    string
+	created time.Time
+}
+
+// list - копии в каталоге, от новых к старым.
+func (m *Manager) list() ([]backup, error) {
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, m.prefix) || !strings.HasSuffix(name, m.ext) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, m.prefix), m.ext)
+		created, err := time.Parse(timeFormat, stamp)
+		if err != nil {
+			continue // чужой файл с похожим именем
+		}
+		backups = append(backups, backup{filepath.Join(m.dir, name), created})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].created.After(backups[j].created) })
+	return backups, nil
+}
+
+// rotate - удаление копий сверх keep и старше maxAge. Самая новая копия сохраняется всегда.
+func (m *Manager) rotate() error {
+	backups, err := m.list()
+	if err != nil {
+		return err
+	}
+
+	now := m.now()
+	for i, b := range backups {
+		if i == 0 {
+			continue
+		}
+		expired := m.maxAge > 0 && now.Sub(b.created) > m.maxAge
+		if (m.keep > 0 && i >= m.keep) || expired {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	dir := t.TempDir()
+	db := storage.New()
+	db.Set("key", "https://example.com")
+
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := New(db, filepath.Join(dir, "db.json"), "", 3, 48*time.Hour)
+	m.now = func() time.Time { return clock }
+
+	t.Run("snapshot is loadable", func(t *testing.T) {
+		path, err := m.Snapshot()
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(dir, "backups", "db-20260101T000000.000Z.json"), path)
+
+		restored := storage.New()
+		require.NoError(t, restored.LoadFromFile(path))
+		value, _ := restored.Get("key")
+		require.Equal(t, "https://example.com", value)
+	})
+
+	t.Run("keeps last N", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			clock = clock.Add(time.Minute)
+			_, err := m.Snapshot()
+			require.NoError(t, err)
+		}
+
+		paths, err := m.List()
+		require.NoError(t, err)
+		require.Len(t, paths, 3)
+		require.Equal(t, filepath.Join(dir, "backups", "db-20260101T000400.000Z.json"), paths[0])
+	})
+
+	t.Run("removes expired", func(t *testing.T) {
+		clock = clock.Add(72 * time.Hour)
+		_, err := m.Snapshot()
+		require.NoError(t, err)
+
+		paths, err := m.List()
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+	})
+
+	t.Run("ignores foreign files", func(t *testing.T) {
+		foreign := filepath.Join(dir, "backups", "db-latest.json")
+		require.NoError(t, os.WriteFile(foreign, []byte("[]"), 0644))
+
+		clock = clock.Add(time.Minute)
+		_, err := m.Snapshot()
+		require.NoError(t, err)
+
+		_, err = os.Stat(foreign)
+		require.NoError(t, err)
+	})
+}
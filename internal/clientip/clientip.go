@@ -0,0 +1,235 @@
+// Package clientip - определение адреса клиента за доверенными прокси.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type ctxKey struct{}
+
+// Resolver - определение адреса клиента. Заголовкам прокси верит,
+// только если запрос пришёл с адреса из списка доверенных.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// New - резолвер с доверенными прокси cidrs. Допускаются и отдельные адреса.
+func New(cidrs []string) (*Resolver, error) {
+	res := &Resolver{}
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			cidr = fmt.Sprintf("%s/%d", cidr, bits)
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		res.trusted = append(res.trusted, network)
+	}
+	return res, nil
+}
+
+// ParseList - разбор списка через запятую.
+func ParseList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Middleware - определение адреса клиента и сохранение его в контексте запроса.
+func (res *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ctxKey{}, res.IP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromRequest - адрес клиента из контекста запроса,
+// без Middleware - адрес соединения.
+func FromRequest(r *http.Request) string {
+	if ip := FromContext(r.Context()); ip != "" {
+		return ip
+	}
+	return hostIP(r.RemoteAddr)
+}
+
+// FromContext - адрес клиента, сохранённый Middleware, или пустая строка.
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(ctxKey{}).(string)
+	return ip
+}
+
+// IP - адрес клиента. Цепочка из Forwarded (RFC 7239), а без него из X-Forwarded-For,
+// просматривается справа налево до первого адреса, не принадлежащего доверенным прокси.
+func (res *Resolver) IP(r *http.Request) string {
+	peer := hostIP(r.RemoteAddr)
+	if !res.isTrusted(peer) {
+		return peer
+	}
+
+	hops := forwarded(r.Header)
+	if hops == nil {
+		hops = forwardedFor(r.Header)
+	}
+	if len(hops) > 0 {
+		for i := len(hops) - 1; i >= 0; i-- {
+			if !res.isTrusted(hops[i]) {
+				return hops[i]
+			}
+		}
+		// Вся цепочка из доверенных прокси - клиент самый левый
+		return hops[0]
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return peer
+}
+
+// isTrusted - принадлежит ли адрес доверенному прокси.
+func (res *Resolver) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range res.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor - адреса из всех заголовков X-Forwarded-For по порядку.
+// Цепочка обрывается на первом нечитаемом адресе справа.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, value := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			return hops[i+1:]
+		}
+		hops[i] = ip.String()
+	}
+	return hops
+}
+
+// forwarded - адреса for из всех заголовков Forwarded по порядку, nil - заголовка нет.
+// Цепочка обрывается справа на первом элементе без адреса: неизвестном ("unknown"),
+// скрытом идентификаторе ("_hidden") или нечитаемом значении.
+func forwarded(h http.Header) []string {
+	values := h.Values("Forwarded")
+	if len(values) == 0 {
+		return nil
+	}
+
+	hops := []string{}
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			hop := ""
+			for _, pair := range splitQuoted(element, ';') {
+				name, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(strings.TrimSpace(name), "for") {
+					hop = nodeIP(unquote(strings.TrimSpace(val)))
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i] == "" {
+			return hops[i+1:]
+		}
+	}
+	return hops
+}
+
+// nodeIP - адрес из идентификатора узла RFC 7239: "192.0.2.43", "192.0.2.43:47011",
+// "[2001:db8::17]" или "[2001:db8::17]:4711". Пустая строка, если адреса нет.
+func nodeIP(node string) string {
+	if strings.HasPrefix(node, "[") {
+		end := strings.Index(node, "]")
+		if end < 0 {
+			return ""
+		}
+		node = node[1:end]
+	} else if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+
+	ip := net.ParseIP(node)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// splitQuoted - разбиение по sep вне кавычек.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote - значение без кавычек quoted-string.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// hostIP - адрес без порта.
+func hostIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,138 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver(t *testing.T) {
+	res, err := New([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.5:1234",
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "untrusted peer headers ignored",
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}, "X-Real-Ip": {"1.2.3.4"}},
+			want:       "203.0.113.5",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "spoofed left entries skipped",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1, 198.51.100.7, 192.168.1.1"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "multiple headers",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.7", "10.0.0.2"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "real ip from trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Real-Ip": {"198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "only trusted hops",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "forwarded header",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"Forwarded": {`for=1.1.1.1, for=198.51.100.7;proto=https, for="10.0.0.2:8080";by=10.0.0.1`}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "forwarded ipv6 with port",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"Forwarded": {`For="[2001:db8:cafe::17]:4711"`}},
+			want:       "2001:db8:cafe::17",
+		},
+		{
+			name:       "forwarded takes precedence",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"Forwarded": {"for=198.51.100.7"}, "X-Forwarded-For": {"1.1.1.1"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "forwarded unknown stops the chain",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"Forwarded": {"for=1.1.1.1, for=unknown, for=10.0.0.2"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "forwarded quoted separators",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"Forwarded": {`for=198.51.100.7;host="a,b;c"`}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "garbage stops the chain",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"not-an-ip, 10.0.0.2"}},
+			want:       "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, values := range tt.headers {
+				for _, v := range values {
+					req.Header.Add(key, v)
+				}
+			}
+			require.Equal(t, tt.want, res.IP(req))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	res, err := New([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	require.Equal(t, "10.0.0.1", FromRequest(req))
+
+	var ip string
+	res.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip = FromRequest(r)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "198.51.100.7", ip)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+	_, err = New([]string{"proxy.local"})
+	require.Error(t, err)
+
+	require.Equal(t, []string{"10.0.0.0/8", "::1"}, ParseList(" 10.0.0.0/8, ,::1 "))
+	require.Empty(t, ParseList(""))
+}
@@ -2,17 +2,77 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"net/url"
+	"os"
+	"time"
 
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
 	"github.com/caarlos0/env/v11"
 )
 
 const defaultAddress = "localhost:8080"
 
+// Бэкенды хранилища.
+const (
+	BackendFile = "file" // данные в памяти, сохранение в JSON-файл
+	BackendKV   = "kv"   // встроенная дисковая база
+)
+
 type Config struct {
 	ServerAddress string `env:"SERVER_ADDRESS"` // envDefault:"localhost:8080"`
+	AdminAddress  string `env:"ADMIN_ADDRESS"`  // служебный адрес с /metrics, пустой - отключён
 	BaseURL       string `env:"BASE_URL"`       // envDefault:"http://localhost:8080"`
 	FileStorage   string `env:"FILE_STORAGE_PATH"`
+	Backend       string `env:"STORAGE_BACKEND"`    // file или kv
+	KVStorage     string `env:"KV_STORAGE_PATH"`    // файл базы для бэкенда kv
+	SecretKey     string `env:"SECRET_KEY"`         // ключ подписи cookie, при пустом значении генерируется и сохраняется рядом с хранилищем
+	APIKeysFile   string `env:"API_KEYS_FILE"`      // файл API-ключей
+	AdminToken    string `env:"ADMIN_TOKEN"`        // токен /api/admin, пустой - API отключён
+	AuditLog      string `env:"AUDIT_LOG"`          // файл журнала аудита, пустой - отключён
+	ForceStorage  bool   `env:"FILE_STORAGE_FORCE"` // разрешить перезапись файла, который не удалось загрузить
+
+	StorageKey     string `env:"STORAGE_KEY"`      // ключ шифрования файла "<id>:<base64>"
+	StorageKeyFile string `env:"STORAGE_KEY_FILE"` // файл ключей, по одному на строку
+
+	CacheSize int           `env:"CACHE_SIZE"` // 0 - без кэша
+	CacheTTL  time.Duration `env:"CACHE_TTL"`
+
+	BloomFPRate float64 `env:"BLOOM_FP_RATE"` // доля ложных срабатываний фильтра Блума, 0 или меньше - без фильтра
+
+	TrustedProxies string `env:"TRUSTED_PROXIES"` // CIDR доверенных прокси через запятую
+
+	RateLimitWrite         float64       `env:"RATE_LIMIT_WRITE"` // запросов в секунду на создание и удаление, 0 - без ограничения
+	RateLimitWriteBurst    int           `env:"RATE_LIMIT_WRITE_BURST"`
+	RateLimitRedirect      float64       `env:"RATE_LIMIT_REDIRECT"` // переходов в секунду, 0 - без ограничения
+	RateLimitRedirectBurst int           `env:"RATE_LIMIT_REDIRECT_BURST"`
+	RateLimitIdle          time.Duration `env:"RATE_LIMIT_IDLE"` // время жизни неактивной корзины
+
+	LinkAccessTTL           time.Duration `env:"LINK_ACCESS_TTL"`           // время жизни пропуска к защищённой паролем ссылке
+	PasswordAttempts        int           `env:"PASSWORD_ATTEMPTS"`         // попыток ввода пароля подряд для одной ссылки, 0 - без ограничения
+	PasswordAttemptInterval time.Duration `env:"PASSWORD_ATTEMPT_INTERVAL"` // время восстановления одной попытки, 0 - без ограничения
+	PendingPage             string        `env:"PENDING_PAGE"`              // HTML-страница до начала действия ссылки, пустая - ответ 404
+
+	LogLevel  string `env:"LOG_LEVEL"`  // debug, info, warn или error
+	LogFormat string `env:"LOG_FORMAT"` // json или console
+	LogFile   string `env:"LOG_FILE"`   // пустой - stderr
+
+	AccessLog            string        `env:"ACCESS_LOG"`              // файл журнала запросов, пустой - отключён
+	AccessLogFormat      string        `env:"ACCESS_LOG_FORMAT"`       // combined или json
+	AccessLogMaxSize     int64         `env:"ACCESS_LOG_MAX_SIZE"`     // размер файла для ротации в байтах, 0 - без ротации по размеру
+	AccessLogMaxInterval time.Duration `env:"ACCESS_LOG_MAX_INTERVAL"` // время до ротации, 0 - без ротации по времени
+	AccessLogKeep        int           `env:"ACCESS_LOG_KEEP"`         // число хранимых сжатых файлов
+
+	TraceExporter    string  `env:"TRACE_EXPORTER"`              // none, stdout, file или otlp
+	TraceFile        string  `env:"TRACE_FILE"`                  // файл спанов для экспортёра file
+	TraceEndpoint    string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"` // URL коллектора для экспортёра otlp
+	TraceSampleRatio float64 `env:"TRACE_SAMPLE_RATIO"`          // доля трассируемых запросов от 0 до 1
+
+	AutosaveInterval time.Duration `env:"AUTOSAVE_INTERVAL"` // 0 - сохранение только при завершении
+	BackupDir        string        `env:"BACKUP_DIR"`        // по умолчанию backups рядом с файлом хранилища
+	BackupInterval   time.Duration `env:"BACKUP_INTERVAL"`   // 0 - копии только по запросу
+	BackupKeep       int           `env:"BACKUP_KEEP"`       // 0 - без ограничения
+	BackupMaxAge     time.Duration `env:"BACKUP_MAX_AGE"`    // 0 - без ограничения
 }
 
 func NewConfig() (Config, error) {
@@ -26,23 +86,196 @@ func NewConfig() (Config, error) {
 	configFlags := Config{}
 
 	flag.StringVar(&configFlags.ServerAddress, "a", defaultAddress, "Server address")
+	flag.StringVar(&configFlags.AdminAddress, "admin-addr", "", "Admin listener address for /metrics, empty to disable")
 	flag.StringVar(&configFlags.BaseURL, "b", "http://"+defaultAddress, "Base URL")
 	flag.StringVar(&configFlags.FileStorage, "f", "data/db.json", "File Storage")
+	flag.StringVar(&configFlags.Backend, "storage", BackendFile, "Storage backend: file or kv")
+	flag.StringVar(&configFlags.KVStorage, "kv", "data/db.bolt", "KV storage file")
+	flag.StringVar(&configFlags.SecretKey, "k", "", "Cookie signing key")
+	flag.StringVar(&configFlags.APIKeysFile, "api-keys", "data/apikeys.json", "API keys file")
+	flag.StringVar(&configFlags.AdminToken, "admin-token", "", "Admin API bearer token, empty to disable")
+	flag.StringVar(&configFlags.AuditLog, "audit-log", "data/audit.jsonl", "Audit log file, empty to disable")
+	flag.BoolVar(&configFlags.ForceStorage, "force-storage", false, "Overwrite storage file that failed to load")
+	flag.StringVar(&configFlags.StorageKey, "storage-key", "", "Storage encryption key <id>:<base64>")
+	flag.StringVar(&configFlags.StorageKeyFile, "storage-key-file", "", "Storage encryption key file")
+	flag.IntVar(&configFlags.CacheSize, "cache-size", 0, "Lookup cache size, 0 to disable")
+	flag.DurationVar(&configFlags.CacheTTL, "cache-ttl", time.Minute, "Lookup cache TTL")
+	flag.Float64Var(&configFlags.BloomFPRate, "bloom-fp", 0.01, "Bloom filter false positive rate, 0 or less to disable")
+	flag.StringVar(&configFlags.TrustedProxies, "trusted-proxies", "", "Trusted proxy CIDRs, comma separated")
+	flag.Float64Var(&configFlags.RateLimitWrite, "rate-write", 5, "Write requests per second per client, 0 to disable")
+	flag.IntVar(&configFlags.RateLimitWriteBurst, "rate-write-burst", 50, "Write requests burst per client")
+	flag.Float64Var(&configFlags.RateLimitRedirect, "rate-redirect", 50, "Redirects per second per client, 0 to disable")
+	flag.IntVar(&configFlags.RateLimitRedirectBurst, "rate-redirect-burst", 200, "Redirects burst per client")
+	flag.DurationVar(&configFlags.RateLimitIdle, "rate-idle", 10*time.Minute, "Idle rate limit bucket expiry")
+	flag.DurationVar(&configFlags.LinkAccessTTL, "link-access-ttl", 15*time.Minute, "Access cookie lifetime for password-protected links")
+	flag.IntVar(&configFlags.PasswordAttempts, "password-attempts", 5, "Password attempts in a row per link, 0 to disable")
+	flag.DurationVar(&configFlags.PasswordAttemptInterval, "password-attempt-interval", time.Minute, "Time to regain one password attempt")
+	flag.StringVar(&configFlags.PendingPage, "pending-page", "", "HTML page served before a link becomes active, empty for 404")
+	flag.StringVar(&configFlags.LogLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	flag.StringVar(&configFlags.LogFormat, "log-format", "console", "Log format: json or console")
+	flag.StringVar(&configFlags.LogFile, "log-file", "", "Log file, empty for stderr")
+	flag.StringVar(&configFlags.AccessLog, "access-log", "", "Access log file, empty to disable")
+	flag.StringVar(&configFlags.AccessLogFormat, "access-log-format", "combined", "Access log format: combined or json")
+	flag.Int64Var(&configFlags.AccessLogMaxSize, "access-log-max-size", 100<<20, "Access log size to rotate at, 0 to disable")
+	flag.DurationVar(&configFlags.AccessLogMaxInterval, "access-log-max-interval", 24*time.Hour, "Access log age to rotate at, 0 to disable")
+	flag.IntVar(&configFlags.AccessLogKeep, "access-log-keep", 7, "Number of rotated access logs to keep")
+	flag.StringVar(&configFlags.TraceExporter, "trace", "none", "Trace exporter: none, stdout, file or otlp")
+	flag.StringVar(&configFlags.TraceFile, "trace-file", "data/traces.jsonl", "Trace file for the file exporter")
+	flag.StringVar(&configFlags.TraceEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector URL")
+	flag.Float64Var(&configFlags.TraceSampleRatio, "trace-sample", 1, "Fraction of requests to trace, 0 to 1")
+	flag.DurationVar(&configFlags.AutosaveInterval, "autosave", 0, "Autosave interval, 0 to save on shutdown only")
+	flag.StringVar(&configFlags.BackupDir, "backup-dir", "", "Backup directory")
+	flag.DurationVar(&configFlags.BackupInterval, "backup-interval", 24*time.Hour, "Backup interval, 0 for backups on request only")
+	flag.IntVar(&configFlags.BackupKeep, "backup-keep", 10, "Number of backups to keep, 0 for unlimited")
+	flag.DurationVar(&configFlags.BackupMaxAge, "backup-max-age", 7*24*time.Hour, "Maximum backup age, 0 for unlimited")
 	flag.Parse()
 
 	if config.ServerAddress == "" {
 		config.ServerAddress = configFlags.ServerAddress
 	}
+	if config.AdminAddress == "" {
+		config.AdminAddress = configFlags.AdminAddress
+	}
 	if config.BaseURL == "" {
 		config.BaseURL = configFlags.BaseURL
 	}
 	if config.FileStorage == "" {
 		config.FileStorage = configFlags.FileStorage
 	}
+	if config.Backend == "" {
+		config.Backend = configFlags.Backend
+	}
+	if config.KVStorage == "" {
+		config.KVStorage = configFlags.KVStorage
+	}
+	if config.SecretKey == "" {
+		config.SecretKey = configFlags.SecretKey
+	}
+	if config.APIKeysFile == "" {
+		config.APIKeysFile = configFlags.APIKeysFile
+	}
+	if config.AdminToken == "" {
+		config.AdminToken = configFlags.AdminToken
+	}
+	if config.AuditLog == "" {
+		config.AuditLog = configFlags.AuditLog
+	}
+	if !config.ForceStorage {
+		config.ForceStorage = configFlags.ForceStorage
+	}
+	if config.StorageKey == "" {
+		config.StorageKey = configFlags.StorageKey
+	}
+	if config.StorageKeyFile == "" {
+		config.StorageKeyFile = configFlags.StorageKeyFile
+	}
+	if config.CacheSize == 0 {
+		config.CacheSize = configFlags.CacheSize
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = configFlags.CacheTTL
+	}
+	if _, ok := os.LookupEnv("BLOOM_FP_RATE"); !ok {
+		config.BloomFPRate = configFlags.BloomFPRate
+	}
+	if config.TrustedProxies == "" {
+		config.TrustedProxies = configFlags.TrustedProxies
+	}
+	// Для лимитов 0 означает отключение, поэтому флаг используется, только если переменная не задана
+	if _, ok := os.LookupEnv("RATE_LIMIT_WRITE"); !ok {
+		config.RateLimitWrite = configFlags.RateLimitWrite
+	}
+	if config.RateLimitWriteBurst == 0 {
+		config.RateLimitWriteBurst = configFlags.RateLimitWriteBurst
+	}
+	if _, ok := os.LookupEnv("RATE_LIMIT_REDIRECT"); !ok {
+		config.RateLimitRedirect = configFlags.RateLimitRedirect
+	}
+	if config.RateLimitRedirectBurst == 0 {
+		config.RateLimitRedirectBurst = configFlags.RateLimitRedirectBurst
+	}
+	if config.RateLimitIdle == 0 {
+		config.RateLimitIdle = configFlags.RateLimitIdle
+	}
+	if config.LinkAccessTTL == 0 {
+		config.LinkAccessTTL = configFlags.LinkAccessTTL
+	}
+	if _, ok := os.LookupEnv("PASSWORD_ATTEMPTS"); !ok {
+		config.PasswordAttempts = configFlags.PasswordAttempts
+	}
+	if _, ok := os.LookupEnv("PASSWORD_ATTEMPT_INTERVAL"); !ok {
+		config.PasswordAttemptInterval = configFlags.PasswordAttemptInterval
+	}
+	if config.PendingPage == "" {
+		config.PendingPage = configFlags.PendingPage
+	}
+	if config.LogLevel == "" {
+		config.LogLevel = configFlags.LogLevel
+	}
+	if config.LogFormat == "" {
+		config.LogFormat = configFlags.LogFormat
+	}
+	if config.LogFile == "" {
+		config.LogFile = configFlags.LogFile
+	}
+	if config.AccessLog == "" {
+		config.AccessLog = configFlags.AccessLog
+	}
+	if config.AccessLogFormat == "" {
+		config.AccessLogFormat = configFlags.AccessLogFormat
+	}
+	if _, ok := os.LookupEnv("ACCESS_LOG_MAX_SIZE"); !ok {
+		config.AccessLogMaxSize = configFlags.AccessLogMaxSize
+	}
+	if _, ok := os.LookupEnv("ACCESS_LOG_MAX_INTERVAL"); !ok {
+		config.AccessLogMaxInterval = configFlags.AccessLogMaxInterval
+	}
+	if config.AccessLogKeep == 0 {
+		config.AccessLogKeep = configFlags.AccessLogKeep
+	}
+	if config.TraceExporter == "" {
+		config.TraceExporter = configFlags.TraceExporter
+	}
+	if config.TraceFile == "" {
+		config.TraceFile = configFlags.TraceFile
+	}
+	if config.TraceEndpoint == "" {
+		config.TraceEndpoint = configFlags.TraceEndpoint
+	}
+	if _, ok := os.LookupEnv("TRACE_SAMPLE_RATIO"); !ok {
+		config.TraceSampleRatio = configFlags.TraceSampleRatio
+	}
+	if config.AutosaveInterval == 0 {
+		config.AutosaveInterval = configFlags.AutosaveInterval
+	}
+	if config.BackupDir == "" {
+		config.BackupDir = configFlags.BackupDir
+	}
+	if _, ok := os.LookupEnv("BACKUP_INTERVAL"); !ok {
+		config.BackupInterval = configFlags.BackupInterval
+	}
+	if _, ok := os.LookupEnv("BACKUP_KEEP"); !ok {
+		config.BackupKeep = configFlags.BackupKeep
+	}
+	if _, ok := os.LookupEnv("BACKUP_MAX_AGE"); !ok {
+		config.BackupMaxAge = configFlags.BackupMaxAge
+	}
 
 	if _, err := url.ParseRequestURI(config.BaseURL); err != nil {
 		return config, err
 	}
+	if _, err := clientip.New(clientip.ParseList(config.TrustedProxies)); err != nil {
+		return config, err
+	}
+	if config.BloomFPRate >= 1 {
+		return config, fmt.Errorf("bloom filter false positive rate must be below 1, got %v", config.BloomFPRate)
+	}
+	if config.TraceSampleRatio < 0 || config.TraceSampleRatio > 1 {
+		return config, fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", config.TraceSampleRatio)
+	}
+	if config.Backend != BackendFile && config.Backend != BackendKV {
+		return config, fmt.Errorf("unknown storage backend %q", config.Backend)
+	}
 
 	return config, nil
 }
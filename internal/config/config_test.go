@@ -4,6 +4,7 @@ import (
 	"flag"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -16,6 +17,9 @@ func TestConfig(t *testing.T) {
 		require.Equal(t, "localhost:8080", cfg.ServerAddress)
 		require.Equal(t, "http://localhost:8080", cfg.BaseURL)
 		require.Equal(t, "data/db.json", cfg.FileStorage)
+		require.Equal(t, 5.0, cfg.RateLimitWrite)
+		require.Equal(t, 50.0, cfg.RateLimitRedirect)
+		require.Equal(t, 5, cfg.PasswordAttempts)
 	})
 
 	t.Run("invalid base URL panics", func(t *testing.T) {
@@ -41,4 +45,70 @@ func TestConfig(t *testing.T) {
 		require.Equal(t, "https://example.com", cfg.BaseURL)
 		require.Equal(t, "/tmp/db.json", cfg.FileStorage)
 	})
+
+	t.Run("zero rate limit from environment", func(t *testing.T) {
+		t.Setenv("RATE_LIMIT_WRITE", "0")
+		t.Setenv("RATE_LIMIT_REDIRECT", "0")
+
+		flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+		cfg, err := NewConfig()
+		require.NoError(t, err)
+		require.Zero(t, cfg.RateLimitWrite)
+		require.Zero(t, cfg.RateLimitRedirect)
+	})
+
+	t.Run("zero password attempts from environment", func(t *testing.T) {
+		t.Setenv("PASSWORD_ATTEMPTS", "0")
+
+		flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+		cfg, err := NewConfig()
+		require.NoError(t, err)
+		require.Zero(t, cfg.PasswordAttempts)
+		require.Equal(t, time.Minute, cfg.PasswordAttemptInterval)
+	})
+
+	t.Run("unlimited backups from environment", func(t *testing.T) {
+		t.Setenv("BACKUP_KEEP", "0")
+		t.Setenv("BACKUP_MAX_AGE", "0")
+
+		flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+		cfg, err := NewConfig()
+		require.NoError(t, err)
+		require.Zero(t, cfg.BackupKeep)
+		require.Zero(t, cfg.BackupMaxAge)
+	})
+
+	t.Run("access log rotation disabled from environment", func(t *testing.T) {
+		t.Setenv("ACCESS_LOG_MAX_SIZE", "0")
+		t.Setenv("ACCESS_LOG_MAX_INTERVAL", "0")
+
+		flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+		cfg, err := NewConfig()
+		require.NoError(t, err)
+		require.Zero(t, cfg.AccessLogMaxSize)
+		require.Zero(t, cfg.AccessLogMaxInterval)
+	})
+
+	t.Run("bloom filter disabled from environment", func(t *testing.T) {
+		t.Setenv("BLOOM_FP_RATE", "0")
+
+		flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+		cfg, err := NewConfig()
+		require.NoError(t, err)
+		require.Zero(t, cfg.BloomFPRate)
+	})
+
+	t.Run("trace sample ratio from environment", func(t *testing.T) {
+		t.Setenv("TRACE_SAMPLE_RATIO", "0")
+
+		flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+		cfg, err := NewConfig()
+		require.NoError(t, err)
+		require.Zero(t, cfg.TraceSampleRatio)
+
+		t.Setenv("TRACE_SAMPLE_RATIO", "1.5")
+		flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+		_, err = NewConfig()
+		require.ErrorContains(t, err, "trace sample ratio")
+	})
 }
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/service"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateAPIKey - создание API-ключа. Ключ возвращается только в этом ответе.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req model.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.DailyQuota < 0 || req.TotalQuota < 0 {
+		http.Error(w, "Quota must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	token, key, err := h.keys.Create(req.Owner, req.Name, req.DailyQuota, req.TotalQuota)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit.Report(r.Context(), audit.Event{
+		Actor:  audit.ActorAdmin,
+		IP:     clientip.FromRequest(r),
+		Action: audit.ActionAPIKeyCreate,
+		Key:    key.ID,
+	})
+
+	resp := apiKeyResponse(key)
+	resp.Key = token
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListAPIKeys - все API-ключи без секретов.
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys := h.keys.List()
+	resp := make([]model.APIKey, len(keys))
+	for i, key := range keys {
+		resp[i] = apiKeyResponse(key)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RevokeAPIKey - отзыв API-ключа.
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	err := h.keys.Revoke(id)
+	switch {
+	case errors.Is(err, apikey.ErrNotFound):
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit.Report(r.Context(), audit.Event{
+		Actor:  audit.ActorAdmin,
+		IP:     clientip.FromRequest(r),
+		Action: audit.ActionAPIKeyRevoke,
+		Key:    id,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiKeyResponse - представление ключа в ответе.
+func apiKeyResponse(key apikey.Key) model.APIKey {
+	return model.APIKey{
+		ID:         key.ID,
+		Owner:      key.Owner,
+		Name:       key.Name,
+		DailyQuota: key.DailyQuota,
+		TotalQuota: key.TotalQuota,
+		UsedTotal:  key.Usage.Total,
+		CreatedAt:  key.CreatedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// SearchURLs - поиск ссылок по подстроке ключа (key), исходного URL (url) и владельцу (owner)
+// с постраничной выдачей (см. listQuery). Курсор следующей страницы - в заголовке X-Next-Cursor.
+func (h *Handler) SearchURLs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := storage.Query{
+		Key:   query.Get("key"),
+		URL:   query.Get("url"),
+		Owner: query.Get("owner"),
+	}
+	if err := listQuery(r, &q); err != nil {
+		http.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.service.Search(r.Context(), q)
+	switch {
+	case errors.Is(err, storage.ErrInvalidCursor):
+		http.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]model.AdminURL, len(page.Records))
+	for i, rec := range page.Records {
+		resp[i] = h.adminURLResponse(rec)
+	}
+
+	writeNextCursor(w, page)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// DisableURL - отключение ссылки: переход по ней возвращает 451.
+func (h *Handler) DisableURL(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+// EnableURL - повторное включение отключённой ссылки.
+func (h *Handler) EnableURL(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+// setDisabled - отключение или включение ссылки.
+func (h *Handler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	key := chi.URLParam(r, "id")
+	_, after, err := h.service.SetDisabled(r.Context(), key, disabled)
+	if !h.writeAdminError(w, err) {
+		return
+	}
+	h.writeAdminURL(w, after)
+}
+
+// ReassignURL - передача ссылки другому владельцу.
+func (h *Handler) ReassignURL(w http.ResponseWriter, r *http.Request) {
+	var req model.OwnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	key := chi.URLParam(r, "id")
+	_, after, err := h.service.Reassign(r.Context(), key, req.UserID)
+	if !h.writeAdminError(w, err) {
+		return
+	}
+	h.writeAdminURL(w, after)
+}
+
+// DeleteUserURLsAdmin - удаление всех ссылок пользователя.
+func (h *Handler) DeleteUserURLsAdmin(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.service.DeleteUserURLs(r.Context(), chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.DeletedURLs{Deleted: len(deleted)})
+}
+
+// writeAdminError - ответ на ошибку операции над ссылкой. false - ответ уже отправлен.
+func (h *Handler) writeAdminError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(w, "URL not found", http.StatusNotFound)
+		return false
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// writeAdminURL - ссылка в ответе.
+func (h *Handler) writeAdminURL(w http.ResponseWriter, rec storage.Record) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.adminURLResponse(rec))
+}
+
+// adminURLResponse - представление записи в административном API.
+func (h *Handler) adminURLResponse(rec storage.Record) model.AdminURL {
+	return model.AdminURL{
+		Key:         rec.ShortURL,
+		ShortURL:    h.service.ShortURL(rec.ShortURL),
+		OriginalURL: rec.OriginalURL,
+		UserID:      rec.UserID,
+		Deleted:     rec.DeletedFlag,
+		Disabled:    rec.Disabled,
+	}
+}
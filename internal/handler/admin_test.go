@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandlers(t *testing.T) {
+	keys, err := apikey.Open(filepath.Join(t.TempDir(), "apikeys.json"))
+	require.NoError(t, err)
+
+	cfg := config.Config{BaseURL: "http://localhost:8080"}
+	h := New(&cfg, storage.New(), WithAPIKeys(keys))
+
+	var created model.APIKey
+
+	t.Run("create", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/admin/keys",
+			strings.NewReader(`{"owner":"service","name":"ci","total_quota":1}`))
+		w := httptest.NewRecorder()
+		h.CreateAPIKey(w, req)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+		require.NotEmpty(t, created.Key)
+		require.Equal(t, "service", created.Owner)
+		require.Equal(t, 1, created.TotalQuota)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		for _, body := range []string{`{`, `{"daily_quota":-1}`} {
+			w := httptest.NewRecorder()
+			h.CreateAPIKey(w, httptest.NewRequest("POST", "/api/admin/keys", strings.NewReader(body)))
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("shorten within quota", func(t *testing.T) {
+		key, err := keys.Authenticate(created.Key)
+		require.NoError(t, err)
+		ctx := apikey.WithKey(context.Background(), key)
+
+		w := httptest.NewRecorder()
+		h.Post(w, httptest.NewRequest("POST", "/", strings.NewReader("https://example.com")).WithContext(ctx))
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "1", w.Header().Get("X-Quota-Total-Limit"))
+		require.Equal(t, "0", w.Header().Get("X-Quota-Total-Remaining"))
+
+		w = httptest.NewRecorder()
+		h.Post(w, httptest.NewRequest("POST", "/", strings.NewReader("https://example.org")).WithContext(ctx))
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.Equal(t, "0", w.Header().Get("X-Quota-Total-Remaining"))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ListAPIKeys(w, httptest.NewRequest("GET", "/api/admin/keys", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var list []model.APIKey
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		require.Len(t, list, 1)
+		require.Empty(t, list[0].Key)
+		require.Equal(t, 1, list[0].UsedTotal)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		revoke := func(id string) int {
+			w := httptest.NewRecorder()
+			h.RevokeAPIKey(w, withID(httptest.NewRequest("DELETE", "/api/admin/keys/"+id, nil), "id", id))
+			return w.Code
+		}
+
+		require.Equal(t, http.StatusNoContent, revoke(created.ID))
+		require.Equal(t, http.StatusNotFound, revoke("unknown"))
+
+		_, err := keys.Authenticate(created.Key)
+		require.ErrorIs(t, err, apikey.ErrInvalid)
+	})
+}
+
+// withID - запрос с параметром маршрута name.
+func withID(r *http.Request, name, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(name, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestLinkModeration(t *testing.T) {
+	ctx := context.Background()
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.Open(auditPath)
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	cfg := config.Config{BaseURL: "http://localhost:8080"}
+	db := storage.New()
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "spam1", OriginalURL: "https://spam.example/1", UserID: "spammer"}))
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "spam2", OriginalURL: "https://spam.example/2", UserID: "spammer"}))
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "good", OriginalURL: "https://example.com", UserID: "user1", Version: 1}))
+	h := New(&cfg, db, WithAudit(auditLog))
+
+	t.Run("search", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.SearchURLs(w, httptest.NewRequest("GET", "/api/admin/urls?url=spam.example&limit=1", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp []model.AdminURL
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Equal(t, []model.AdminURL{{
+			Key:         "spam1",
+			ShortURL:    "http://localhost:8080/spam1",
+			OriginalURL: "https://spam.example/1",
+			UserID:      "spammer",
+		}}, resp)
+
+		w = httptest.NewRecorder()
+		h.SearchURLs(w, httptest.NewRequest("GET", "/api/admin/urls?limit=0", nil))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.DisableURL(w, withID(httptest.NewRequest("POST", "/api/admin/urls/good/disable", nil), "id", "good"))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"disabled":true`)
+
+		w = httptest.NewRecorder()
+		h.Get(w, withID(httptest.NewRequest("GET", "/good", nil), "id", "good"))
+		require.Equal(t, http.StatusUnavailableForLegalReasons, w.Code)
+		require.Contains(t, w.Body.String(), "disabled")
+
+		w = httptest.NewRecorder()
+		h.EnableURL(w, withID(httptest.NewRequest("POST", "/api/admin/urls/good/enable", nil), "id", "good"))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		h.Get(w, withID(httptest.NewRequest("GET", "/good", nil), "id", "good"))
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+		w = httptest.NewRecorder()
+		h.DisableURL(w, withID(httptest.NewRequest("POST", "/api/admin/urls/unknown/disable", nil), "id", "unknown"))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("stale version after moderation", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/api/urls/good", strings.NewReader(`{"title":"Good"}`))
+		req.Header.Set("If-Match", `"1"`)
+		req = withID(req.WithContext(auth.WithUserID(req.Context(), "user1")), "id", "good")
+		w := httptest.NewRecorder()
+		h.PatchURL(w, req)
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+		rec, err := db.Find(ctx, "good")
+		require.NoError(t, err)
+		require.Equal(t, 3, rec.Version)
+		require.False(t, rec.UpdatedAt.IsZero())
+	})
+
+	t.Run("reassign", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("PUT", "/api/admin/urls/good/owner", strings.NewReader(`{"user_id":"user2"}`))
+		h.ReassignURL(w, withID(req, "id", "good"))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		rec, err := db.Find(ctx, "good")
+		require.NoError(t, err)
+		require.Equal(t, "user2", rec.UserID)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest("PUT", "/api/admin/urls/good/owner", strings.NewReader(`{}`))
+		h.ReassignURL(w, withID(req, "id", "good"))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("delete user URLs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.DeleteUserURLsAdmin(w, withID(httptest.NewRequest("DELETE", "/api/admin/users/spammer/urls", nil), "userID", "spammer"))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"deleted":2}`, w.Body.String())
+
+		records, err := db.FindByUser(ctx, "spammer")
+		require.NoError(t, err)
+		require.Empty(t, records)
+	})
+
+	t.Run("audit log", func(t *testing.T) {
+		data, err := os.ReadFile(auditPath)
+		require.NoError(t, err)
+
+		var actions []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var ev audit.Event
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			require.Equal(t, audit.ActorAdmin, ev.Actor)
+			actions = append(actions, ev.Action)
+		}
+		require.Equal(t, []string{
+			audit.ActionLinkDisable,
+			audit.ActionLinkEnable,
+			audit.ActionLinkReassign,
+			audit.ActionLinkDelete,
+			audit.ActionLinkDelete,
+		}, actions)
+	})
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+)
+
+// Ограничения выдачи журнала аудита.
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// errAuditLimit - выдача достигла лимита, обход журнала прекращается.
+var errAuditLimit = errors.New("audit limit reached")
+
+// GetAudit - события журнала аудита в порядке записи.
+// Фильтры: from и to (RFC 3339), actor, action, key; limit - число событий.
+func (h *Handler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := defaultAuditLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxAuditLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	events := []audit.Event{}
+	err = h.audit.Query(filter, func(ev audit.Event) error {
+		events = append(events, ev)
+		if len(events) == limit {
+			return errAuditLimit
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errAuditLimit) {
+		logger.FromContext(r.Context()).Errorw("audit query failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// ExportAudit - выгрузка событий журнала аудита в JSONL с теми же фильтрами, что и GetAudit, без лимита.
+func (h *Handler) ExportAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r)
+	if err != nil {
+		http.Error(w, "Invalid filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+
+	enc := json.NewEncoder(w)
+	err = h.audit.Query(filter, func(ev audit.Event) error {
+		return enc.Encode(ev)
+	})
+	if err != nil {
+		// Заголовки уже отправлены, выгрузка просто обрывается
+		logger.FromContext(r.Context()).Errorw("audit export failed", "error", err)
+	}
+}
+
+// auditFilter - фильтр событий из параметров запроса.
+func auditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Key:    query.Get("key"),
+	}
+
+	for name, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return audit.Filter{}, fmt.Errorf("%s: expected RFC 3339 time", name)
+		}
+		*dst = t
+	}
+	return filter, nil
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHandlers(t *testing.T) {
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	cfg := config.Config{BaseURL: "http://localhost:8080"}
+	h := New(&cfg, storage.New(), WithAudit(auditLog))
+
+	resolver, err := clientip.New(nil)
+	require.NoError(t, err)
+	post := resolver.Middleware(http.HandlerFunc(h.Post))
+
+	shorten := func(userID, url string) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(url))
+		req = req.WithContext(auth.WithUserID(context.Background(), userID))
+		w := httptest.NewRecorder()
+		post.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+	since := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	shorten("user1", "https://example.com")
+	shorten("user2", "https://example.org")
+	shorten("user1", "https://example.net")
+
+	t.Run("query by actor", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.GetAudit(w, httptest.NewRequest("GET", "/api/admin/audit?actor=user1&from="+since, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var events []audit.Event
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&events))
+		require.Len(t, events, 2)
+		for _, ev := range events {
+			require.Equal(t, "user1", ev.Actor)
+			require.Equal(t, audit.ActionLinkCreate, ev.Action)
+			require.Equal(t, "192.0.2.1", ev.IP)
+		}
+	})
+
+	t.Run("time range and limit", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.GetAudit(w, httptest.NewRequest("GET", "/api/admin/audit?to="+since, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `[]`, w.Body.String())
+
+		w = httptest.NewRecorder()
+		h.GetAudit(w, httptest.NewRequest("GET", "/api/admin/audit?limit=1", nil))
+		var events []audit.Event
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&events))
+		require.Len(t, events, 1)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		for _, query := range []string{"from=yesterday", "limit=-1", "limit=100000"} {
+			w := httptest.NewRecorder()
+			h.GetAudit(w, httptest.NewRequest("GET", "/api/admin/audit?"+query, nil))
+			require.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ExportAudit(w, httptest.NewRequest("GET", "/api/admin/audit/export?actor=user2", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		var lines int
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var ev audit.Event
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+			require.Equal(t, "user2", ev.Actor)
+			lines++
+		}
+		require.Equal(t, 1, lines)
+	})
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+)
+
+func (h *Handler) PostBackup(w http.ResponseWriter, r *http.Request) {
+	path, err := h.backups.Snapshot()
+	if err != nil && path == "" {
+		logger.FromContext(r.Context()).Errorw("backup failed", "error", err)
+		http.Error(w, "Backup failed", http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Warnw("backup created, rotation failed", "file", path, "error", err) // копия создана, не удалось удалить старые
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(model.Backup{File: path})
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupHandler(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Config{BaseURL: "http://localhost:8080", FileStorage: filepath.Join(dir, "db.json"), BackupKeep: 2}
+	db := storage.New()
+	db.Set("key", "https://example.com")
+	h := New(&cfg, db)
+
+	w := httptest.NewRecorder()
+	h.PostBackup(w, httptest.NewRequest("POST", "/api/internal/backup", nil))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp model.Backup
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, filepath.Join(dir, "backups"), filepath.Dir(resp.File))
+
+	restored := storage.New()
+	require.NoError(t, restored.LoadFromFile(resp.File))
+	require.Equal(t, 1, restored.Count())
+}
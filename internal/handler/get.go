@@ -1,13 +1,25 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
-	"net/url"
-	"strings"
 
+	"github.com/ParkhomenkoDV/URLShortener/internal/service"
 	"github.com/go-chi/chi/v5"
 )
 
+// disabledPage - страница вместо перехода по ссылке, отключённой модератором.
+const disabledPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Link unavailable</title></head>
+<body>
+<h1>Link unavailable</h1>
+<p>This link has been disabled by the service administrator.</p>
+</body>
+</html>
+`
+
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
@@ -15,22 +27,57 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	originalURL, exists := h.db.Get(id)
-
-	if !exists {
-		http.Error(w, "URL not found", http.StatusNotFound)
+	originalURL, code, err := h.service.RedirectWithAccess(r.Context(), id, accessToken(r))
+	if errors.Is(err, service.ErrPasswordRequired) {
+		writePasswordPage(w, http.StatusUnauthorized, "")
 		return
 	}
-
-	if !strings.HasPrefix(originalURL, "http://") && !strings.HasPrefix(originalURL, "https://") {
-		originalURL = "http://" + originalURL
-	}
-
-	if _, err := url.ParseRequestURI(originalURL); err != nil {
-		http.Error(w, "Invalid URL format", http.StatusInternalServerError)
+	if !h.writeRedirectError(w, err) {
 		return
 	}
 
 	w.Header().Set("Location", originalURL)
-	w.WriteHeader(http.StatusTemporaryRedirect)
+	w.WriteHeader(code)
+}
+
+// writeRedirectError - ответ на ошибку перехода по ссылке. false - ответ уже отправлен.
+// До начала действия ссылки отдаётся страница-заглушка или 404 без кэширования, чтобы ссылка
+// заработала точно в назначенное время.
+func (h *Handler) writeRedirectError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrNotActive) && h.pending != nil:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		w.Write(h.pending)
+		return false
+	case errors.Is(err, service.ErrNotActive):
+		w.Header().Set("Cache-Control", "no-store")
+		http.Error(w, "URL not found", http.StatusNotFound)
+		return false
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(w, "URL not found", http.StatusNotFound)
+		return false
+	case errors.Is(err, service.ErrGone):
+		http.Error(w, "URL deleted", http.StatusGone)
+		return false
+	case errors.Is(err, service.ErrExpired):
+		http.Error(w, "URL expired", http.StatusGone)
+		return false
+	case errors.Is(err, service.ErrExhausted):
+		http.Error(w, "URL click limit reached", http.StatusGone)
+		return false
+	case errors.Is(err, service.ErrDisabled):
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusUnavailableForLegalReasons)
+		io.WriteString(w, disabledPage)
+		return false
+	case errors.Is(err, service.ErrInvalidURL):
+		http.Error(w, "Invalid URL format", http.StatusInternalServerError)
+		return false
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	return true
 }
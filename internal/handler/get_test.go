@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ParkhomenkoDV/URLShortener/internal/config"
 	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
@@ -17,9 +18,15 @@ func TestGetHandler(t *testing.T) {
 	db := storage.New()
 	h := New(&cfg, db)
 
-	h.db.Set("validID", "https://example.com")
-	h.db.Set("noScheme", "example.com")
-	h.db.Set("invalidURL", "http://invalid url.com")
+	db.Set("validID", "https://example.com")
+	db.Set("noScheme", "example.com")
+	db.Set("invalidURL", "http://invalid url.com")
+	db.Set("deletedID", "https://example.org")
+	db.MarkDeleted(context.Background(), "deletedID")
+	db.Put(storage.Record{ShortURL: "disabledID", OriginalURL: "https://malware.example", Disabled: true})
+	db.Put(storage.Record{ShortURL: "expiredID", OriginalURL: "https://expired.example", ExpiresAt: time.Now().Add(-time.Minute)})
+	db.Put(storage.Record{ShortURL: "exhaustedID", OriginalURL: "https://invite.example", MaxClicks: 1, Clicks: 1})
+	db.Put(storage.Record{ShortURL: "permanentID", OriginalURL: "https://moved.example", RedirectCode: http.StatusMovedPermanently})
 
 	tests := []struct {
 		name       string
@@ -46,6 +53,27 @@ func TestGetHandler(t *testing.T) {
 			name:       "ID not found",
 			id:         "nonExistentID",
 			wantStatus: http.StatusNotFound,
+		}, {
+			name:       "deleted ID",
+			id:         "deletedID",
+			wantStatus: http.StatusGone,
+		}, {
+			name:       "disabled ID",
+			id:         "disabledID",
+			wantStatus: http.StatusUnavailableForLegalReasons,
+		}, {
+			name:       "expired ID",
+			id:         "expiredID",
+			wantStatus: http.StatusGone,
+		}, {
+			name:       "exhausted ID",
+			id:         "exhaustedID",
+			wantStatus: http.StatusGone,
+		}, {
+			name:       "custom redirect code",
+			id:         "permanentID",
+			wantStatus: http.StatusMovedPermanently,
+			wantURL:    "https://moved.example",
 		}, {
 			name:       "invalid URL format",
 			id:         "invalidURL",
@@ -76,3 +104,43 @@ func TestGetHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestGetActivationWindow(t *testing.T) {
+	db := storage.New()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := WithClock(func() time.Time { return now })
+	db.Put(storage.Record{
+		ShortURL:    "launchID",
+		OriginalURL: "https://launch.example",
+		NotBefore:   now.Add(time.Hour),
+		ExpiresAt:   now.Add(2 * time.Hour),
+	})
+
+	get := func(h *Handler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.Get(w, withID(httptest.NewRequest("GET", "/launchID", nil), "id", "launchID"))
+		return w
+	}
+
+	cfg := config.Config{BaseURL: "http://localhost:8080"}
+	plain := New(&cfg, db, clock)
+	placeholder := New(&cfg, db, clock, WithPendingPage([]byte("<h1>Coming soon</h1>")))
+
+	w := get(plain)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	w = get(placeholder)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	require.Equal(t, "<h1>Coming soon</h1>", w.Body.String())
+
+	now = now.Add(time.Hour)
+	w = get(plain)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	require.Equal(t, "https://launch.example", w.Header().Get("Location"))
+
+	now = now.Add(time.Hour)
+	require.Equal(t, http.StatusGone, get(plain).Code)
+	require.Equal(t, http.StatusGone, get(placeholder).Code)
+}
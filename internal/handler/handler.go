@@ -1,19 +1,85 @@
 package handler
 
 import (
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/backup"
 	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/ratelimit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/service"
 	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
 )
 
 type Handler struct {
-	//repo   storage.Repository // not used yet
-	config config.Config
-	db     *storage.DB
+	config  config.Config
+	db      storage.Store
+	service *service.Shortener
+	backups *backup.Manager
+	keys    *apikey.Store
+	audit   *audit.Log
+	now     func() time.Time // nil - системные часы
+	pending []byte           // страница до начала действия ссылки, nil - ответ 404
+}
+
+// Option - настройка обработчиков.
+type Option func(*Handler)
+
+// WithAPIKeys - управление API-ключами keys и учёт их квот при создании ссылок.
+func WithAPIKeys(keys *apikey.Store) Option {
+	return func(h *Handler) {
+		h.keys = keys
+	}
+}
+
+// WithAudit - запись действий в журнал аудита log.
+func WithAudit(log *audit.Log) Option {
+	return func(h *Handler) {
+		h.audit = log
+	}
+}
+
+// WithClock - источник текущего времени для сроков действия ссылок.
+func WithClock(now func() time.Time) Option {
+	return func(h *Handler) {
+		h.now = now
+	}
 }
 
-func New(config *config.Config, db *storage.DB) *Handler {
-	return &Handler{
-		config: *config,
-		db:     db,
+// WithPendingPage - HTML-страница page вместо ответа 404 для ссылки, срок действия которой ещё не начался.
+func WithPendingPage(page []byte) Option {
+	return func(h *Handler) {
+		h.pending = page
+	}
+}
+
+func New(config *config.Config, db storage.Store, opts ...Option) *Handler {
+	h := &Handler{
+		config:  *config,
+		db:      db,
+		backups: backup.New(db, config.FileStorage, config.BackupDir, config.BackupKeep, config.BackupMaxAge),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	serviceOpts := []service.Option{
+		service.WithAudit(h.audit),
+		service.WithLinkAccess(config.SecretKey, config.LinkAccessTTL),
+	}
+	// Нулевое число попыток или интервал отключают ограничение
+	var attempts ratelimit.Limit
+	if config.PasswordAttempts > 0 && config.PasswordAttemptInterval > 0 {
+		attempts = ratelimit.Limit{Rate: 1 / config.PasswordAttemptInterval.Seconds(), Burst: config.PasswordAttempts}
+	}
+	serviceOpts = append(serviceOpts, service.WithPasswordAttempts(attempts))
+	if h.keys != nil {
+		serviceOpts = append(serviceOpts, service.WithQuota(h.keys))
+	}
+	if h.now != nil {
+		serviceOpts = append(serviceOpts, service.WithClock(h.now))
 	}
+	h.service = service.New(db, config.BaseURL, serviceOpts...)
+	return h
 }
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/service"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetURL - ссылка пользователя с версией и историей изменений. Версия возвращается в ETag.
+func (h *Handler) GetURL(w http.ResponseWriter, r *http.Request) {
+	rec, err := h.service.Link(r.Context(), auth.UserID(r.Context()), chi.URLParam(r, "id"))
+	if !writeLinkError(w, err) {
+		return
+	}
+	h.writeURLDetails(w, rec)
+}
+
+// PatchURL - изменение цели, кода перенаправления, срока действия, метаданных, описания и пароля ссылки.
+// С заголовком If-Match изменение применяется, только если версия ссылки совпадает с ETag.
+func (h *Handler) PatchURL(w http.ResponseWriter, r *http.Request) {
+	var req model.URLPatch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	patch := service.Patch{
+		OriginalURL:  req.OriginalURL,
+		RedirectCode: req.RedirectCode,
+		Metadata:     req.Metadata,
+		Title:        req.Title,
+		Tags:         req.Tags,
+		Notes:        req.Notes,
+		Password:     req.Password,
+	}
+	var err error
+	if patch.ExpiresAt, err = optionalTime(req.ExpiresAt); err != nil {
+		http.Error(w, "Invalid expires_at", http.StatusBadRequest)
+		return
+	}
+	if patch.NotBefore, err = optionalTime(req.NotBefore); err != nil {
+		http.Error(w, "Invalid not_before", http.StatusBadRequest)
+		return
+	}
+
+	if header := r.Header.Get("If-Match"); header != "" {
+		versions, ok := parseIfMatch(header)
+		if !ok {
+			http.Error(w, "Invalid If-Match", http.StatusBadRequest)
+			return
+		}
+		patch.IfMatch = versions
+	}
+
+	rec, err := h.service.UpdateLink(r.Context(), auth.UserID(r.Context()), chi.URLParam(r, "id"), patch)
+	switch {
+	case errors.Is(err, service.ErrInvalidURL):
+		http.Error(w, "Invalid URL format", http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrInvalidRedirect):
+		http.Error(w, "Invalid redirect code", http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrInvalidDetails):
+		http.Error(w, "Invalid title, tags or notes", http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrInvalidPassword):
+		http.Error(w, "Invalid password", http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrInvalidWindow):
+		http.Error(w, "not_after must be later than not_before", http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrConflict):
+		http.Error(w, "URL already exists", http.StatusConflict)
+		return
+	case errors.Is(err, service.ErrPrecondition):
+		http.Error(w, "URL version mismatch", http.StatusPreconditionFailed)
+		return
+	}
+	if !writeLinkError(w, err) {
+		return
+	}
+	h.writeURLDetails(w, rec)
+}
+
+// optionalTime - время из необязательного поля JSON. nil - поля нет, для null - нулевое время.
+func optionalTime(raw json.RawMessage) (*time.Time, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var t time.Time
+	if !bytes.Equal(raw, []byte("null")) {
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+// parseIfMatch - версии из заголовка If-Match. Для "*" возвращается пустой список - без проверки.
+// Слабые метки (W/) сравниваются как сильные: версия однозначно определяет состояние ссылки.
+func parseIfMatch(header string) ([]int, bool) {
+	if strings.TrimSpace(header) == "*" {
+		return nil, true
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return nil, false
+		}
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil {
+			return nil, false
+		}
+		versions = append(versions, version)
+	}
+	return versions, true
+}
+
+// GetURLStats - число переходов, ограничение переходов и окно действия ссылки пользователя.
+func (h *Handler) GetURLStats(w http.ResponseWriter, r *http.Request) {
+	rec, err := h.service.Link(r.Context(), auth.UserID(r.Context()), chi.URLParam(r, "id"))
+	if !writeLinkError(w, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.URLStats{
+		Key:       rec.ShortURL,
+		Status:    h.service.Status(rec),
+		Clicks:    rec.Clicks,
+		MaxClicks: rec.MaxClicks,
+		NotBefore: timePtr(rec.NotBefore),
+		NotAfter:  timePtr(rec.ExpiresAt),
+	})
+}
+
+// writeLinkError - ответ на ошибку операции владельца над ссылкой. false - ответ уже отправлен.
+func writeLinkError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(w, "URL not found", http.StatusNotFound)
+		return false
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	case errors.Is(err, service.ErrGone):
+		http.Error(w, "URL deleted", http.StatusGone)
+		return false
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// writeURLDetails - ссылка в ответе с версией в ETag.
+func (h *Handler) writeURLDetails(w http.ResponseWriter, rec storage.Record) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(rec.Version)))
+	json.NewEncoder(w).Encode(h.urlDetailsResponse(rec))
+}
+
+// urlDetailsResponse - представление записи для владельца.
+func (h *Handler) urlDetailsResponse(rec storage.Record) model.URLDetails {
+	resp := model.URLDetails{
+		Key:          rec.ShortURL,
+		ShortURL:     h.service.ShortURL(rec.ShortURL),
+		OriginalURL:  rec.OriginalURL,
+		RedirectCode: redirectCode(rec.RedirectCode),
+		ExpiresAt:    timePtr(rec.ExpiresAt),
+		NotBefore:    timePtr(rec.NotBefore),
+		Status:       h.service.Status(rec),
+		Metadata:     rec.Metadata,
+		Title:        rec.Title,
+		Tags:         rec.Tags,
+		Notes:        rec.Notes,
+		Protected:    rec.PasswordHash != "",
+		Clicks:       rec.Clicks,
+		MaxClicks:    rec.MaxClicks,
+		Version:      rec.Version,
+		UpdatedAt:    rec.UpdatedAt,
+	}
+	for _, rev := range rec.History {
+		resp.History = append(resp.History, model.URLRevision{
+			Version:      rev.Version,
+			OriginalURL:  rev.OriginalURL,
+			RedirectCode: redirectCode(rev.RedirectCode),
+			ExpiresAt:    timePtr(rev.ExpiresAt),
+			ReplacedAt:   rev.ReplacedAt,
+		})
+	}
+	return resp
+}
+
+// redirectCode - код перенаправления с учётом значения по умолчанию.
+func redirectCode(code int) int {
+	if code == 0 {
+		return http.StatusTemporaryRedirect
+	}
+	return code
+}
+
+// timePtr - указатель на время, nil для нулевого.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
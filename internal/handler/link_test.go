@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkHandlers(t *testing.T) {
+	cfg := config.Config{BaseURL: "http://localhost:8080"}
+	db := storage.New()
+	h := New(&cfg, db)
+
+	ctx := context.Background()
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: "https://example.com", UserID: "user1", Version: 1}))
+
+	patch := func(userID, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/urls/key1", strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		req = withID(req.WithContext(auth.WithUserID(req.Context(), userID)), "id", "key1")
+		w := httptest.NewRecorder()
+		h.PatchURL(w, req)
+		return w
+	}
+
+	t.Run("get", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/urls/key1", nil)
+		req = withID(req.WithContext(auth.WithUserID(req.Context(), "user1")), "id", "key1")
+		w := httptest.NewRecorder()
+		h.GetURL(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `"1"`, w.Header().Get("ETag"))
+		var resp model.URLDetails
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Equal(t, model.URLDetails{
+			Key:          "key1",
+			ShortURL:     "http://localhost:8080/key1",
+			OriginalURL:  "https://example.com",
+			RedirectCode: http.StatusTemporaryRedirect,
+			Status:       storage.StatusActive,
+			Version:      1,
+		}, resp)
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		w := patch("user1", `{"original_url":"https://example.org","redirect_code":308,"expires_at":"2030-01-01T00:00:00Z","metadata":{"team":"growth"}}`, `"1"`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+		var resp model.URLDetails
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Equal(t, "https://example.org", resp.OriginalURL)
+		require.Equal(t, http.StatusPermanentRedirect, resp.RedirectCode)
+		require.NotNil(t, resp.ExpiresAt)
+		require.Equal(t, map[string]string{"team": "growth"}, resp.Metadata)
+		require.Len(t, resp.History, 1)
+		require.Equal(t, "https://example.com", resp.History[0].OriginalURL)
+
+		w = httptest.NewRecorder()
+		h.Get(w, withID(httptest.NewRequest("GET", "/key1", nil), "id", "key1"))
+		require.Equal(t, http.StatusPermanentRedirect, w.Code)
+		require.Equal(t, "https://example.org", w.Header().Get("Location"))
+	})
+
+	t.Run("clear expiry", func(t *testing.T) {
+		w := patch("user1", `{"expires_at":null,"metadata":{"team":null}}`, `W/"2", "5"`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp model.URLDetails
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Nil(t, resp.ExpiresAt)
+		require.Nil(t, resp.Metadata)
+		require.Equal(t, 3, resp.Version)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			userID  string
+			body    string
+			ifMatch string
+			want    int
+		}{
+			{"stale version", "user1", `{}`, `"1"`, http.StatusPreconditionFailed},
+			{"invalid If-Match", "user1", `{}`, `1`, http.StatusBadRequest},
+			{"invalid JSON", "user1", `{`, "", http.StatusBadRequest},
+			{"invalid URL", "user1", `{"original_url":"not a url"}`, "", http.StatusBadRequest},
+			{"invalid code", "user1", `{"redirect_code":200}`, "", http.StatusBadRequest},
+			{"invalid expiry", "user1", `{"expires_at":"tomorrow"}`, "", http.StatusBadRequest},
+			{"foreign link", "user2", `{}`, "*", http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				require.Equal(t, tt.want, patch(tt.userID, tt.body, tt.ifMatch).Code)
+			})
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		notBefore := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		w := patch("user1", `{"not_before":"2030-01-01T00:00:00Z","expires_at":"2030-02-01T00:00:00Z"}`, "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		req := httptest.NewRequest("GET", "/api/urls/key1/stats", nil)
+		req = withID(req.WithContext(auth.WithUserID(req.Context(), "user1")), "id", "key1")
+		w = httptest.NewRecorder()
+		h.GetURLStats(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp model.URLStats
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		notAfter := notBefore.AddDate(0, 1, 0)
+		require.Equal(t, model.URLStats{
+			Key:       "key1",
+			Status:    storage.StatusScheduled,
+			Clicks:    1, // переход из проверки смены кода перенаправления
+			NotBefore: &notBefore,
+			NotAfter:  &notAfter,
+		}, resp)
+
+		w = patch("user1", `{"not_before":"2030-03-01T00:00:00Z"}`, "")
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		w = patch("user1", `{"not_before":null,"expires_at":null}`, "")
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestParseIfMatch(t *testing.T) {
+	versions, ok := parseIfMatch(`"3", W/"4"`)
+	require.True(t, ok)
+	require.Equal(t, []int{3, 4}, versions)
+
+	versions, ok = parseIfMatch(" * ")
+	require.True(t, ok)
+	require.Empty(t, versions)
+
+	_, ok = parseIfMatch(`"abc"`)
+	require.False(t, ok)
+}
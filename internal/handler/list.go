@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// NextCursorHeader - заголовок с курсором следующей страницы выдачи.
+const NextCursorHeader = "X-Next-Cursor"
+
+// Ограничения страницы выдачи ссылок.
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// listQuery - разбор параметров выдачи ссылок:
+// limit, cursor, sort (key, created_at, clicks, с "-" - в обратном порядке),
+// status (active, scheduled, expired, deleted через запятую), from и to (RFC 3339) - интервал времени создания.
+func listQuery(r *http.Request, q *storage.Query) error {
+	query := r.URL.Query()
+
+	q.Limit = defaultSearchLimit
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxSearchLimit {
+			return errors.New("invalid limit")
+		}
+		q.Limit = limit
+	}
+
+	if raw := query.Get("sort"); raw != "" {
+		q.Sort, q.Desc = strings.CutPrefix(raw, "-")
+		if !slices.Contains([]string{storage.SortKey, storage.SortCreated, storage.SortClicks}, q.Sort) {
+			return errors.New("invalid sort")
+		}
+	}
+
+	if raw := query.Get("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			if !slices.Contains([]string{storage.StatusActive, storage.StatusScheduled, storage.StatusExpired, storage.StatusDeleted}, status) {
+				return errors.New("invalid status")
+			}
+			q.Status = append(q.Status, status)
+		}
+	}
+
+	for name, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if raw := query.Get(name); raw != "" {
+			var err error
+			if *t, err = time.Parse(time.RFC3339, raw); err != nil {
+				return errors.New("invalid " + name)
+			}
+		}
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		after, err := storage.ParseCursor(raw)
+		if err != nil {
+			return err
+		}
+		q.After = &after
+	}
+	return nil
+}
+
+// writeNextCursor - курсор следующей страницы в заголовке ответа.
+func writeNextCursor(w http.ResponseWriter, page storage.Page) {
+	if page.Next != nil {
+		w.Header().Set(NextCursorHeader, page.Next.String())
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_listQuery(t *testing.T) {
+	cursor := storage.Cursor{Sort: storage.SortClicks, Desc: true, Clicks: 5, Key: "abc"}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    storage.Query
+		wantErr bool
+	}{
+		{name: "defaults", query: "", want: storage.Query{Limit: defaultSearchLimit}},
+		{
+			name:  "all parameters",
+			query: "limit=10&sort=-clicks&status=active,+expired&from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z&cursor=" + cursor.String(),
+			want: storage.Query{
+				Limit:  10,
+				Sort:   storage.SortClicks,
+				Desc:   true,
+				Status: []string{storage.StatusActive, storage.StatusExpired},
+				From:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+				After:  &cursor,
+			},
+		},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "limit too large", query: "limit=1001", wantErr: true},
+		{name: "unknown sort", query: "sort=title", wantErr: true},
+		{name: "unknown status", query: "status=active,gone", wantErr: true},
+		{name: "invalid time", query: "from=yesterday", wantErr: true},
+		{name: "invalid cursor", query: "cursor=bad", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q storage.Query
+			err := listQuery(httptest.NewRequest("GET", "/?"+tt.query, nil), &q)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, q)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// AccessCookieName - имя cookie с пропуском к защищённой паролем ссылке.
+// Cookie ограничена путём ссылки, поэтому пропуска разных ссылок не пересекаются.
+const AccessCookieName = "link_access"
+
+// passwordPage - форма ввода пароля вместо перехода по защищённой ссылке.
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Password required</title></head>
+<body>
+<h1>Password required</h1>
+<p>This link is protected. Enter the password to continue.</p>
+{{if .}}<p role="alert">{{.}}</p>
+{{end}}<form method="post">
+<input type="password" name="password" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// Unlock - проверка пароля из формы защищённой ссылки. При верном пароле выдаётся cookie
+// с пропуском и выполняется перенаправление на ссылку, при неверном форма показывается снова.
+func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return
+	}
+
+	access, err := h.service.Unlock(r.Context(), id, r.PostFormValue("password"))
+	switch {
+	case errors.Is(err, service.ErrWrongPassword):
+		writePasswordPage(w, http.StatusUnauthorized, "Incorrect password.")
+		return
+	case errors.Is(err, service.ErrTooManyAttempts):
+		writePasswordPage(w, http.StatusTooManyRequests, "Too many attempts. Try again later.")
+		return
+	}
+	if !h.writeRedirectError(w, err) {
+		return
+	}
+
+	shortURL := h.service.ShortURL(id)
+	if access.Token != "" {
+		u, _ := url.Parse(shortURL)
+		http.SetCookie(w, &http.Cookie{
+			Name:     AccessCookieName,
+			Value:    access.Token,
+			Path:     u.Path,
+			Expires:  access.ExpiresAt,
+			HttpOnly: true,
+			Secure:   u.Scheme == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	http.Redirect(w, r, shortURL, http.StatusSeeOther)
+}
+
+// accessToken - пропуск к ссылке из cookie запроса.
+func accessToken(r *http.Request) string {
+	cookie, err := r.Cookie(AccessCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// writePasswordPage - форма ввода пароля с сообщением message.
+func writePasswordPage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	passwordPage.Execute(w, message)
+}
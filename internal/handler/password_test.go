@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordProtectedLink(t *testing.T) {
+	cfg := config.Config{BaseURL: "http://localhost:8080", PasswordAttempts: 2, PasswordAttemptInterval: time.Hour}
+	db := storage.New()
+	h := New(&cfg, db)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	db.Put(storage.Record{ShortURL: "lockedID", OriginalURL: "https://docs.example", PasswordHash: string(hash)})
+
+	get := func(cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/lockedID", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		h.Get(w, withID(r, "id", "lockedID"))
+		return w
+	}
+	unlock := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		r := httptest.NewRequest("POST", "/lockedID", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.Unlock(w, withID(r, "id", "lockedID"))
+		return w
+	}
+
+	t.Run("form instead of redirect", func(t *testing.T) {
+		w := get()
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		require.Contains(t, w.Body.String(), `<form method="post">`)
+		require.Empty(t, w.Header().Get("Location"))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		w := unlock("wrong")
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Contains(t, w.Body.String(), "Incorrect password")
+		require.Empty(t, w.Result().Cookies())
+	})
+
+	t.Run("correct password", func(t *testing.T) {
+		w := unlock("secret")
+		require.Equal(t, http.StatusSeeOther, w.Code)
+		require.Equal(t, "http://localhost:8080/lockedID", w.Header().Get("Location"))
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.Equal(t, AccessCookieName, cookies[0].Name)
+		require.Equal(t, "/lockedID", cookies[0].Path)
+		require.True(t, cookies[0].HttpOnly)
+
+		w = get(cookies[0])
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		require.Equal(t, "https://docs.example", w.Header().Get("Location"))
+
+		w = get(&http.Cookie{Name: AccessCookieName, Value: "1.forged"})
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("throttled", func(t *testing.T) {
+		w := unlock("secret")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.Contains(t, w.Body.String(), "Too many attempts")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/unknown", strings.NewReader("password=x"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.Unlock(w, withID(r, "id", "unknown"))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -2,14 +2,16 @@ package handler
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
-	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
 	"github.com/ParkhomenkoDV/URLShortener/internal/model"
-	"github.com/ParkhomenkoDV/URLShortener/internal/utils"
+	"github.com/ParkhomenkoDV/URLShortener/internal/service"
 )
 
 func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
@@ -21,13 +23,14 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	originalURL := strings.TrimSpace(string(body))
-	shortURL, err := h.processURL(originalURL)
+	shortURL, status, err := h.shorten(r, originalURL, service.Details{})
+	h.quotaHeaders(w, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 	w.Write([]byte(shortURL))
 }
 
@@ -44,57 +47,115 @@ func (h *Handler) PostJSON(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	shortURL, err := h.processURL(req.URL)
+	details := service.Details{
+		Title:     req.Title,
+		Tags:      req.Tags,
+		Notes:     req.Notes,
+		Password:  req.Password,
+		MaxClicks: req.MaxClicks,
+	}
+	if req.NotBefore != nil {
+		details.NotBefore = *req.NotBefore
+	}
+	if req.NotAfter != nil {
+		details.NotAfter = *req.NotAfter
+	}
+	shortURL, status, err := h.shorten(r, req.URL, details)
+	h.quotaHeaders(w, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(model.Response{Result: shortURL})
 }
 
-// normalizationURL - нормализация url.
-func normalizationURL(rawURL string) string {
-	rawURL = strings.TrimSpace(rawURL)
+func (h *Handler) PostBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "Content-Type must be application/json", http.StatusBadRequest)
+		return
+	}
 
-	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
-		rawURL = "http://" + rawURL
+	var req []model.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
 	}
+	defer r.Body.Close()
 
-	return rawURL
-}
+	if len(req) == 0 {
+		http.Error(w, "Empty batch", http.StatusBadRequest)
+		return
+	}
 
-// validateURL - валидация url.
-func validateURL(rawURL string) error {
-	rawURL = normalizationURL(rawURL)
+	rawURLs := make([]string, len(req))
+	for i, item := range req {
+		rawURLs[i] = item.OriginalURL
+	}
 
-	if _, err := url.ParseRequestURI(rawURL); err != nil {
-		return fmt.Errorf("invalid URL format")
+	shortURLs, err := h.service.ShortenBatch(r.Context(), auth.UserID(r.Context()), rawURLs)
+	h.quotaHeaders(w, r)
+	switch {
+	case errors.Is(err, service.ErrInvalidURL):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrQuota):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	return nil
+	resp := make([]model.BatchResponse, len(req))
+	for i, item := range req {
+		resp[i] = model.BatchResponse{CorrelationID: item.CorrelationID, ShortURL: shortURLs[i]}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
 }
 
-// processURL - сокращение + запись URL.
-func (h *Handler) processURL(rawURL string) (string, error) {
-	if err := validateURL(rawURL); err != nil {
-		return "", err
+// shorten - сокращение URL и выбор HTTP-статуса ответа.
+func (h *Handler) shorten(r *http.Request, rawURL string, d service.Details) (string, int, error) {
+	shortURL, err := h.service.ShortenWithDetails(r.Context(), auth.UserID(r.Context()), rawURL, d)
+	switch {
+	case errors.Is(err, service.ErrConflict):
+		return shortURL, http.StatusConflict, nil
+	case errors.Is(err, service.ErrInvalidURL), errors.Is(err, service.ErrInvalidDetails),
+		errors.Is(err, service.ErrInvalidPassword), errors.Is(err, service.ErrInvalidMaxClicks),
+		errors.Is(err, service.ErrInvalidWindow):
+		return "", http.StatusBadRequest, err
+	case errors.Is(err, service.ErrQuota):
+		return "", http.StatusTooManyRequests, err
+	case err != nil:
+		return "", http.StatusInternalServerError, errors.New("internal server error")
 	}
+	return shortURL, http.StatusCreated, nil
+}
 
-	var shortKey string
-	for {
-		shortKey = utils.GenerateShortURL(8)
-		_, exists := h.db.Get(shortKey)
-		if !exists {
-			break
-		}
+// quotaHeaders - остаток квот API-ключа, которым аутентифицирован запрос.
+func (h *Handler) quotaHeaders(w http.ResponseWriter, r *http.Request) {
+	key, ok := apikey.FromContext(r.Context())
+	if !ok || h.keys == nil {
+		return
+	}
+	q, err := h.keys.Quota(key.ID)
+	if err != nil {
+		return
 	}
 
-	shortURL := h.config.BaseURL + "/" + shortKey
-
-	h.db.Set(shortKey, normalizationURL(rawURL))
-
-	return shortURL, nil
+	header := w.Header()
+	if q.DailyLimit > 0 {
+		header.Set("X-Quota-Daily-Limit", strconv.Itoa(q.DailyLimit))
+		header.Set("X-Quota-Daily-Remaining", strconv.Itoa(q.DailyRemaining))
+		header.Set("X-Quota-Daily-Reset", strconv.FormatInt(q.Reset.Unix(), 10))
+	}
+	if q.TotalLimit > 0 {
+		header.Set("X-Quota-Total-Limit", strconv.Itoa(q.TotalLimit))
+		header.Set("X-Quota-Total-Remaining", strconv.Itoa(q.TotalRemaining))
+	}
 }
@@ -1,14 +1,17 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
 	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
 	"github.com/stretchr/testify/require"
 )
@@ -52,6 +55,121 @@ func TestPostHandler(t *testing.T) {
 		require.Contains(t, result.Result, "http://localhost:8080/")
 	})
 
+	t.Run("JSON request with details", func(t *testing.T) {
+		jsonBody := `{"url":"https://shop.example","title":"Shop","tags":["Promo"],"notes":"Q3 campaign"}`
+		req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.PostJSON(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		rec, err := db.FindByURL(context.Background(), "https://shop.example")
+		require.NoError(t, err)
+		require.Equal(t, "Shop", rec.Title)
+		require.Equal(t, []string{"promo"}, rec.Tags)
+		require.Equal(t, "Q3 campaign", rec.Notes)
+
+		req = httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://shop.example/x","tags":["`+strings.Repeat("x", 100)+`"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		h.PostJSON(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("JSON request with max clicks", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://invite.example","max_clicks":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.PostJSON(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		rec, err := db.FindByURL(context.Background(), "https://invite.example")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), rec.MaxClicks)
+
+		req = httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://invite.example/x","max_clicks":-1}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		h.PostJSON(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("JSON request with activation window", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://launch.example","not_before":"2030-01-01T00:00:00Z","not_after":"2030-02-01T00:00:00Z"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.PostJSON(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		rec, err := db.FindByURL(context.Background(), "https://launch.example")
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), rec.NotBefore)
+		require.Equal(t, time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC), rec.ExpiresAt)
+
+		req = httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://launch.example/x","not_before":"2030-02-01T00:00:00Z","not_after":"2030-01-01T00:00:00Z"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		h.PostJSON(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("JSON request with password", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://docs.example","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.PostJSON(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		rec, err := db.FindByURL(context.Background(), "https://docs.example")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(rec.PasswordHash, "$2"))
+
+		req = httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://docs.example/x","password":"`+strings.Repeat("p", 100)+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		h.PostJSON(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("repeated URL conflict", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader("https://example.net"))
+		w := httptest.NewRecorder()
+		h.Post(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+		first := w.Body.String()
+
+		req = httptest.NewRequest("POST", "/", strings.NewReader("https://example.net"))
+		w = httptest.NewRecorder()
+		h.Post(w, req)
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.Equal(t, first, w.Body.String())
+	})
+
+	t.Run("batch request", func(t *testing.T) {
+		body := `[{"correlation_id":"a","original_url":"https://a.example"},{"correlation_id":"b","original_url":"https://b.example"}]`
+		req := httptest.NewRequest("POST", "/api/shorten/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.PostBatch(w, req)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		var result []model.BatchResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		require.Len(t, result, 2)
+		require.Equal(t, "a", result[0].CorrelationID)
+		require.Contains(t, result[1].ShortURL, "http://localhost:8080/")
+	})
+
+	t.Run("invalid batch", func(t *testing.T) {
+		for _, body := range []string{"[]", `[{"correlation_id":"a","original_url":"y a n d e x"}]`, "{bad}"} {
+			req := httptest.NewRequest("POST", "/api/shorten/batch", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.PostBatch(w, req)
+			require.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
 	t.Run("invalid requests", func(t *testing.T) {
 		tests := []struct {
 			name        string
@@ -78,42 +196,3 @@ func TestPostHandler(t *testing.T) {
 		}
 	})
 }
-
-func Test_normalizationURL(t *testing.T) {
-	tests := []struct {
-		name   string
-		rawURL string
-		want   string
-	}{
-		{"1", "yandex.ru", "http://yandex.ru"},
-		{"2", "http://yandex.ru", "http://yandex.ru"},
-		{"3", "https://yandex.ru", "https://yandex.ru"},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := normalizationURL(tt.rawURL)
-			require.Equal(t, tt.want, got)
-		})
-	}
-}
-
-func Test_validateURL(t *testing.T) {
-	tests := []struct {
-		name    string
-		rawURL  string
-		wantErr bool
-	}{
-		{"1", "yandex.ru", false},
-		{"2", "http://yandex.ru", false},
-		{"3", "https://yandex.ru", false},
-		{"4", "y a n d e x", true},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := validateURL(tt.rawURL); (err != nil) != tt.wantErr {
-				t.Errorf("validateURL() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
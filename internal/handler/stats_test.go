@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler(t *testing.T) {
+	cfg := config.Config{BaseURL: "http://localhost:8080"}
+	db := storage.New()
+	h := New(&cfg, db)
+
+	ctx := context.Background()
+	db.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: "https://example.com", UserID: "user1"})
+	db.Create(ctx, storage.Record{ShortURL: "key2", OriginalURL: "https://google.com", UserID: "user1"})
+	db.Create(ctx, storage.Record{ShortURL: "key3", OriginalURL: "https://github.com", UserID: "user2"})
+
+	w := httptest.NewRecorder()
+	h.GetStats(w, httptest.NewRequest("GET", "/api/internal/stats", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats model.Stats
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	require.Equal(t, model.Stats{URLs: 3, Users: 2}, stats)
+}
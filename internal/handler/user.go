@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/service"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// GetUserURLs - ссылки пользователя с постраничной выдачей (см. listQuery).
+// Параметр tag оставляет ссылки с меткой, q - ссылки, в заголовке или URL которых
+// встречаются все слова запроса. Курсор следующей страницы - в заголовке X-Next-Cursor.
+func (h *Handler) GetUserURLs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := storage.Query{Tag: query.Get("tag"), Text: query.Get("q")}
+	if err := listQuery(r, &q); err != nil {
+		http.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.service.SearchUserURLs(r.Context(), auth.UserID(r.Context()), q)
+	switch {
+	case errors.Is(err, storage.ErrInvalidCursor):
+		http.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	records := page.Records
+	if len(records) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeNextCursor(w, page)
+	resp := make([]model.UserURL, len(records))
+	for i, rec := range records {
+		resp[i] = model.UserURL{
+			ShortURL:    h.service.ShortURL(rec.ShortURL),
+			OriginalURL: rec.OriginalURL,
+			Title:       rec.Title,
+			Tags:        rec.Tags,
+			Notes:       rec.Notes,
+			Protected:   rec.PasswordHash != "",
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) DeleteUserURLs(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	if err := json.NewDecoder(r.Body).Decode(&keys); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	userID := auth.UserID(r.Context())
+	for _, key := range keys {
+		err := h.service.Delete(r.Context(), userID, key)
+		// Чужие, несуществующие и уже удалённые ключи пропускаются
+		if err != nil &&
+			!errors.Is(err, service.ErrNotFound) &&
+			!errors.Is(err, service.ErrForbidden) &&
+			!errors.Is(err, service.ErrGone) {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserURLsHandlers(t *testing.T) {
+	cfg := config.Config{BaseURL: "http://localhost:8080"}
+	db := storage.New()
+	h := New(&cfg, db)
+
+	ctx := context.Background()
+	db.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: "https://example.com", UserID: "user1"})
+	db.Create(ctx, storage.Record{ShortURL: "key2", OriginalURL: "https://google.com", UserID: "user2"})
+
+	withUser := func(r *http.Request, userID string) *http.Request {
+		return r.WithContext(auth.WithUserID(r.Context(), userID))
+	}
+
+	t.Run("list own URLs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.GetUserURLs(w, withUser(httptest.NewRequest("GET", "/api/user/urls", nil), "user1"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp []model.UserURL
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Equal(t, []model.UserURL{{ShortURL: "http://localhost:8080/key1", OriginalURL: "https://example.com"}}, resp)
+	})
+
+	t.Run("filter by tag and text", func(t *testing.T) {
+		db.Create(ctx, storage.Record{ShortURL: "key3", OriginalURL: "https://shop.example", UserID: "user1", Title: "Summer sale", Tags: []string{"promo"}})
+
+		list := func(query string) []model.UserURL {
+			w := httptest.NewRecorder()
+			h.GetUserURLs(w, withUser(httptest.NewRequest("GET", "/api/user/urls?"+query, nil), "user1"))
+			if w.Code == http.StatusNoContent {
+				return nil
+			}
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp []model.UserURL
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			return resp
+		}
+
+		require.Equal(t, []model.UserURL{{
+			ShortURL:    "http://localhost:8080/key3",
+			OriginalURL: "https://shop.example",
+			Title:       "Summer sale",
+			Tags:        []string{"promo"},
+		}}, list("tag=Promo"))
+		require.Len(t, list("q=summer+SALE"), 1)
+		require.Len(t, list("q=example"), 2)
+		require.Empty(t, list("tag=promo&q=winter"))
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		var keys []string
+		cursor := ""
+		for range 3 {
+			w := httptest.NewRecorder()
+			h.GetUserURLs(w, withUser(httptest.NewRequest("GET", "/api/user/urls?sort=-key&limit=1&cursor="+cursor, nil), "user1"))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var resp []model.UserURL
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			require.Len(t, resp, 1)
+			keys = append(keys, strings.TrimPrefix(resp[0].ShortURL, "http://localhost:8080/"))
+
+			cursor = w.Header().Get(NextCursorHeader)
+			if cursor == "" {
+				break
+			}
+		}
+		require.Equal(t, []string{"key3", "key1"}, keys)
+
+		w := httptest.NewRecorder()
+		h.GetUserURLs(w, withUser(httptest.NewRequest("GET", "/api/user/urls?cursor=bad", nil), "user1"))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("no URLs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.GetUserURLs(w, withUser(httptest.NewRequest("GET", "/api/user/urls", nil), "user3"))
+		require.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("delete skips foreign keys", func(t *testing.T) {
+		body := strings.NewReader(`["key1","key2","unknown"]`)
+		w := httptest.NewRecorder()
+		h.DeleteUserURLs(w, withUser(httptest.NewRequest("DELETE", "/api/user/urls", body), "user1"))
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		rec, _ := db.Find(ctx, "key1")
+		require.True(t, rec.DeletedFlag)
+		rec, _ = db.Find(ctx, "key2")
+		require.False(t, rec.DeletedFlag)
+	})
+
+	t.Run("delete invalid JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.DeleteUserURLs(w, withUser(httptest.NewRequest("DELETE", "/api/user/urls", strings.NewReader("{bad}")), "user1"))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
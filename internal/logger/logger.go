@@ -1,32 +1,155 @@
 package logger
 
 import (
-	"net"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-// Singleton для логгера
-var sugar zap.SugaredLogger
+// Форматы логов.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса.
+const RequestIDHeader = "X-Request-ID"
+
+// Максимальная длина идентификатора запроса, принимаемого от клиента.
+const maxRequestIDLength = 128
+
+// Config - параметры логгера.
+type Config struct {
+	Level  string // debug, info, warn, error
+	Format string // FormatJSON или FormatConsole
+	Output string // файл логов, пустой - stderr
+}
+
+// Корневой логгер. До вызова New логи отбрасываются.
+var root atomic.Pointer[zap.Logger]
 
-// Инициализатор для логгера
-func New() {
-	logger, err := zap.NewDevelopment()
+func init() {
+	root.Store(zap.NewNop())
+}
+
+// New - создание корневого логгера по настройкам cfg.
+func New(cfg Config) error {
+	level := zapcore.InfoLevel
+	if cfg.Level != "" {
+		var err error
+		if level, err = zapcore.ParseLevel(cfg.Level); err != nil {
+			return err
+		}
+	}
+
+	zcfg := zap.NewProductionConfig()
+	if cfg.Format == FormatConsole || cfg.Format == "" {
+		zcfg = zap.NewDevelopmentConfig()
+	} else if cfg.Format != FormatJSON {
+		return fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	zcfg.Level = zap.NewAtomicLevelAt(level)
+	zcfg.Development = false
+	zcfg.Sampling = nil
+	if cfg.Output != "" {
+		zcfg.OutputPaths = []string{cfg.Output}
+		zcfg.ErrorOutputPaths = []string{cfg.Output}
+	}
+
+	logger, err := zcfg.Build()
 	if err != nil {
-		panic(err)
+		return err
+	}
+	root.Store(logger)
+	return nil
+}
+
+// L - корневой логгер для кода вне обработки запросов.
+func L() *zap.SugaredLogger {
+	return root.Load().Sugar()
+}
+
+// Sync - выгрузка буферизованных записей.
+func Sync() error {
+	return root.Load().Sync()
+}
+
+type ctxKey struct{}
+type requestIDKey struct{}
+
+// WithContext - контекст с логгером l.
+func WithContext(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext - логгер запроса с его идентификатором или корневой логгер.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok {
+		return l
 	}
-	defer logger.Sync()
+	return L()
+}
+
+// RequestID - идентификатор запроса из контекста.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDMiddleware - идентификатор запроса из X-Request-ID или новый,
+// возвращаемый клиенту и добавляемый ко всем записям логгера запроса.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		l := L().With("request_id", id)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			l = l.With("trace_id", sc.TraceID().String())
+		}
 
-	// делаем регистратор SugaredLogger
-	sugar = *logger.Sugar()
+		next.ServeHTTP(w, r.WithContext(WithContext(ctx, l)))
+	})
+}
+
+// validRequestID - проверка идентификатора, пришедшего от клиента.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
 }
 
-// loggingResponseWriter оборачивает http.ResponseWriter для отслеживания статуса
+// newRequestID - случайный идентификатор запроса.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// loggingResponseWriter оборачивает http.ResponseWriter для отслеживания статуса и размера ответа
 type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int
 }
 
 func (lw *loggingResponseWriter) WriteHeader(code int) {
@@ -34,6 +157,12 @@ func (lw *loggingResponseWriter) WriteHeader(code int) {
 	lw.ResponseWriter.WriteHeader(code)
 }
 
+func (lw *loggingResponseWriter) Write(data []byte) (int, error) {
+	n, err := lw.ResponseWriter.Write(data)
+	lw.bytes += n
+	return n, err
+}
+
 // Middleware для логирования запросов
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -47,34 +176,14 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 
-		// Получаем IP клиента
-		clientIP := getClientIP(r)
-
 		// Логируем информацию о запросе
-		sugar.Infoln(
+		FromContext(r.Context()).Infow("request",
 			"uri", r.RequestURI,
 			"method", r.Method,
 			"status", lw.statusCode,
+			"bytes", lw.bytes,
 			"duration", duration,
-			"client_ip", clientIP,
+			"client_ip", clientip.FromRequest(r),
 		)
 	})
 }
-
-// getClientIP возвращает IP адрес клиента
-func getClientIP(r *http.Request) string {
-	// Пробуем получить IP из заголовков (для прокси)
-	if ip := r.Header.Get("X-Real-IP"); ip != "" {
-		return ip
-	}
-	if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
-		return ip
-	}
-
-	// Возвращаем IP из RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return ip
-}
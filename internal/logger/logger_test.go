@@ -3,14 +3,79 @@ package logger
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
 	"github.com/stretchr/testify/require"
 )
 
+func TestNew(t *testing.T) {
+	t.Run("json file output", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "app.log")
+		require.NoError(t, New(Config{Level: "warn", Format: FormatJSON, Output: file}))
+
+		L().Info("hidden")
+		L().Warnw("shown", "key", "value")
+		require.NoError(t, Sync())
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "hidden")
+		require.Contains(t, string(data), `"msg":"shown","key":"value"`)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		require.Error(t, New(Config{Level: "loud"}))
+		require.Error(t, New(Config{Format: "xml"}))
+	})
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, New(Config{Format: FormatJSON, Output: file}))
+
+	var id string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = RequestID(r.Context())
+		FromContext(r.Context()).Info("inside handler")
+	}))
+
+	t.Run("generated", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+		require.Len(t, id, 16)
+		require.Equal(t, id, rr.Header().Get(RequestIDHeader))
+
+		require.NoError(t, Sync())
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"request_id":"`+id+`"`)
+	})
+
+	t.Run("from client", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, "abc-123", id)
+		require.Equal(t, "abc-123", rr.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("invalid from client", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, "bad id\n")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		require.NotEqual(t, "bad id\n", id)
+		require.Len(t, id, 16)
+	})
+}
+
 func TestLoggingMiddleware(t *testing.T) {
 	t.Run("logging middleware", func(t *testing.T) {
-		New()
+		require.NoError(t, New(Config{Level: "error"}))
 
 		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -28,17 +93,29 @@ func TestLoggingMiddleware(t *testing.T) {
 		require.Equal(t, "response", rr.Body.String())
 	})
 
-	t.Run("get client IP from headers", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/", nil)
-		req.Header.Set("X-Real-IP", "192.168.1.1")
+	t.Run("response size", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		lw := &loggingResponseWriter{ResponseWriter: rr, statusCode: http.StatusOK}
+		lw.Write([]byte("hello "))
+		lw.Write([]byte("world"))
+		require.Equal(t, 11, lw.bytes)
+	})
 
-		ip := getClientIP(req)
-		require.Equal(t, "192.168.1.1", ip)
+	t.Run("client IP from resolver", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "app.log")
+		require.NoError(t, New(Config{Format: FormatJSON, Output: file}))
 
-		req.Header.Del("X-Real-IP")
+		resolver, err := clientip.New([]string{"127.0.0.1"})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "127.0.0.1:8080"
 		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		resolver.Middleware(LoggingMiddleware(http.NotFoundHandler())).ServeHTTP(httptest.NewRecorder(), req)
 
-		ip = getClientIP(req)
-		require.Equal(t, "10.0.0.1", ip)
+		require.NoError(t, Sync())
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"client_ip":"10.0.0.1"`)
 	})
 }
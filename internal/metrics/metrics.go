@@ -0,0 +1,236 @@
+// Package metrics - метрики сервиса в формате Prometheus.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage/bloom"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage/cache"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Префикс имён метрик.
+const namespace = "shortener"
+
+// Метка маршрута для запросов, не попавших ни в один маршрут.
+const unmatchedRoute = "unmatched"
+
+// Metrics - реестр и коллекторы метрик сервиса.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	redirects *prometheus.CounterVec
+	created   prometheus.Counter
+	conflicts prometheus.Counter
+
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+
+	gzipRaw        prometheus.Counter
+	gzipCompressed prometheus.Counter
+	gzipRatio      prometheus.Histogram
+}
+
+// New - создание реестра со всеми метриками сервиса.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Redirects served by status.",
+		}, []string{"status"}),
+		created: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "links_created_total",
+			Help:      "Short links created.",
+		}),
+		conflicts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shorten_conflicts_total",
+			Help:      "Shorten requests answered with 409 Conflict.",
+		}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Storage operation latency.",
+			Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"op"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_errors_total",
+			Help:      "Failed storage operations, not found excluded.",
+		}, []string{"op"}),
+		gzipRaw: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "gzip_uncompressed_bytes_total",
+			Help:      "Response bytes before gzip compression.",
+		}),
+		gzipCompressed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "gzip_compressed_bytes_total",
+			Help:      "Response bytes after gzip compression.",
+		}),
+		gzipRatio: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "gzip_compression_ratio",
+			Help:      "Compressed to uncompressed size ratio per response.",
+			Buckets:   prometheus.LinearBuckets(0.1, 0.1, 15),
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.duration, m.redirects, m.created, m.conflicts,
+		m.storageDuration, m.storageErrors,
+		m.gzipRaw, m.gzipCompressed, m.gzipRatio,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler - обработчик /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// statusWriter - ResponseWriter, запоминающий статус ответа.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.status = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Middleware - учёт запросов, редиректов и конфликтов.
+// Маршрут берётся из шаблона chi, чтобы ключи ссылок не раздували число серий.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := strconv.Itoa(sw.status)
+
+		m.requests.WithLabelValues(route, r.Method, status).Inc()
+		m.duration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+
+		switch {
+		case sw.status >= 300 && sw.status < 400:
+			m.redirects.WithLabelValues(status).Inc()
+		case sw.status == http.StatusConflict:
+			m.conflicts.Inc()
+		}
+	})
+}
+
+// ObserveGzip - учёт сжатия ответа.
+func (m *Metrics) ObserveGzip(raw, compressed int) {
+	if raw == 0 {
+		return
+	}
+	m.gzipRaw.Add(float64(raw))
+	m.gzipCompressed.Add(float64(compressed))
+	m.gzipRatio.Observe(float64(compressed) / float64(raw))
+}
+
+// ObserveStorage - размер хранилища, считываемый при каждом опросе.
+func (m *Metrics) ObserveStorage(repo storage.Repository) {
+	m.registry.MustRegister(&storageCollector{
+		repo:  repo,
+		urls:  prometheus.NewDesc(namespace+"_storage_links", "Active short links in storage.", nil, nil),
+		users: prometheus.NewDesc(namespace+"_storage_users", "Users owning active links.", nil, nil),
+	})
+}
+
+// ObserveCache - счётчики кэша поиска.
+func (m *Metrics) ObserveCache(c *cache.Cache) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_hits_total", Help: "Lookup cache hits.",
+		}, func() float64 { return float64(c.Metrics().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_misses_total", Help: "Lookup cache misses.",
+		}, func() float64 { return float64(c.Metrics().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_evictions_total", Help: "Lookup cache evictions.",
+		}, func() float64 { return float64(c.Metrics().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "cache_entries", Help: "Lookup cache entries.",
+		}, func() float64 { return float64(c.Metrics().Size) }),
+	)
+}
+
+// ObserveBloom - счётчики фильтра Блума.
+func (m *Metrics) ObserveBloom(g *bloom.Guard) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "bloom_rejected_total", Help: "Lookups rejected by the Bloom filter.",
+		}, func() float64 { return float64(g.Metrics().Rejected) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "bloom_false_positives_total", Help: "Lookups passed by the Bloom filter for absent keys.",
+		}, func() float64 { return float64(g.Metrics().FalsePositives) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "bloom_keys", Help: "Keys in the Bloom filter.",
+		}, func() float64 { return float64(g.Metrics().Keys) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "bloom_estimated_fp_rate", Help: "Estimated Bloom filter false positive rate.",
+		}, func() float64 { return g.Metrics().EstimatedFPRate }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "bloom_target_fp_rate", Help: "Configured Bloom filter false positive rate.",
+		}, func() float64 { return g.Metrics().TargetFPRate }),
+	)
+}
+
+// storageCollector - размер хранилища по storage.Repository.Stats.
+type storageCollector struct {
+	repo  storage.Repository
+	urls  *prometheus.Desc
+	users *prometheus.Desc
+}
+
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.urls
+	ch <- c.users
+}
+
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats, err := c.repo.Stats(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.urls, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.urls, prometheus.GaugeValue, float64(stats.URLs))
+	ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(stats.Users))
+}
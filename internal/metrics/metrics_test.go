@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage/bloom"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage/cache"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "https://example.com")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	r.Post("/api/shorten", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	for _, path := range []string{"/abc", "/def"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/shorten", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a/b/c", nil))
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("/{id}", "GET", "307")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues(unmatchedRoute, "GET", "404")))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.redirects.WithLabelValues("307")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.conflicts))
+	require.Equal(t, 3, testutil.CollectAndCount(m.duration))
+}
+
+func TestGzip(t *testing.T) {
+	m := New()
+	m.ObserveGzip(1000, 250)
+	m.ObserveGzip(0, 20)
+
+	require.Equal(t, 1000.0, testutil.ToFloat64(m.gzipRaw))
+	require.Equal(t, 250.0, testutil.ToFloat64(m.gzipCompressed))
+	require.Equal(t, 1, testutil.CollectAndCount(m.gzipRatio))
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+
+	db := m.Instrument(storage.New())
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "key", OriginalURL: "https://example.com", UserID: "user"}))
+
+	c := cache.New(db, 10, time.Minute)
+	m.ObserveCache(c)
+	g, err := bloom.New(ctx, c, 0.01)
+	require.NoError(t, err)
+	m.ObserveBloom(g)
+	m.ObserveStorage(g)
+
+	_, err = g.Find(ctx, "key")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"shortener_links_created_total 1",
+		"shortener_storage_links 1",
+		"shortener_storage_users 1",
+		"shortener_cache_misses_total 1",
+		"shortener_bloom_keys 1",
+		"shortener_bloom_target_fp_rate 0.01",
+		`shortener_storage_operation_duration_seconds_count{op="find"} 1`,
+	} {
+		require.Contains(t, body, line)
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// Store - декоратор хранилища, замеряющий длительность операций.
+type Store struct {
+	storage.Store
+	m *Metrics
+}
+
+// Instrument - обёртка хранилища с замером операций и учётом созданных ссылок.
+func (m *Metrics) Instrument(store storage.Store) *Store {
+	return &Store{Store: store, m: m}
+}
+
+// observe - учёт завершённой операции op, начатой в start.
+func (s *Store) observe(op string, start time.Time, err error) {
+	s.m.storageDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, storage.ErrExhausted) {
+		s.m.storageErrors.WithLabelValues(op).Inc()
+	}
+}
+
+func (s *Store) Find(ctx context.Context, key string) (storage.Record, error) {
+	start := time.Now()
+	rec, err := s.Store.Find(ctx, key)
+	s.observe("find", start, err)
+	return rec, err
+}
+
+func (s *Store) FindByURL(ctx context.Context, originalURL string) (storage.Record, error) {
+	start := time.Now()
+	rec, err := s.Store.FindByURL(ctx, originalURL)
+	s.observe("find_by_url", start, err)
+	return rec, err
+}
+
+func (s *Store) FindByUser(ctx context.Context, userID string) ([]storage.Record, error) {
+	start := time.Now()
+	records, err := s.Store.FindByUser(ctx, userID)
+	s.observe("find_by_user", start, err)
+	return records, err
+}
+
+func (s *Store) Create(ctx context.Context, rec storage.Record) error {
+	start := time.Now()
+	err := s.Store.Create(ctx, rec)
+	s.observe("create", start, err)
+	if err == nil {
+		s.m.created.Inc()
+	}
+	return err
+}
+
+func (s *Store) MarkDeleted(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.Store.MarkDeleted(ctx, key)
+	s.observe("mark_deleted", start, err)
+	return err
+}
+
+func (s *Store) Update(ctx context.Context, key string, fn func(rec *storage.Record) error) (storage.Record, error) {
+	start := time.Now()
+	rec, err := s.Store.Update(ctx, key, fn)
+	s.observe("update", start, err)
+	return rec, err
+}
+
+func (s *Store) Search(ctx context.Context, q storage.Query) (storage.Page, error) {
+	start := time.Now()
+	page, err := s.Store.Search(ctx, q)
+	s.observe("search", start, err)
+	return page, err
+}
+
+func (s *Store) Click(ctx context.Context, key string) (storage.Record, error) {
+	start := time.Now()
+	rec, err := s.Store.Click(ctx, key)
+	s.observe("click", start, err)
+	return rec, err
+}
+
+func (s *Store) Stats(ctx context.Context) (storage.Stats, error) {
+	start := time.Now()
+	stats, err := s.Store.Stats(ctx)
+	s.observe("stats", start, err)
+	return stats, err
+}
+
+func (s *Store) SaveToFile(filePath string) error {
+	start := time.Now()
+	err := s.Store.SaveToFile(filePath)
+	s.observe("save", start, err)
+	return err
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	db := m.Instrument(storage.New())
+
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "key", OriginalURL: "https://example.com"}))
+	require.ErrorIs(t, db.Create(ctx, storage.Record{ShortURL: "key", OriginalURL: "https://google.com"}), storage.ErrKeyExists)
+
+	_, err := db.Find(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = db.FindByURL(ctx, "https://example.com")
+	require.NoError(t, err)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.created))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("create")))
+	require.Zero(t, testutil.ToFloat64(m.storageErrors.WithLabelValues("find")))
+	require.Equal(t, 3, testutil.CollectAndCount(m.storageDuration))
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"compress/gzip"
-	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -28,24 +27,47 @@ func GzipRequestMiddleware(next http.Handler) http.Handler {
 			defer gzRead.Close()
 			r.Body = gzRead
 		}
-		fmt.Println("GzipRequestMiddleware")
 		next.ServeHTTP(w, r)
 	})
 }
 
+// countingWriter - Writer, считающий записанные байты.
+type countingWriter struct {
+	io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(data []byte) (int, error) {
+	n, err := cw.Writer.Write(data)
+	cw.n += n
+	return n, err
+}
+
 func GzipResponseMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			next.ServeHTTP(w, r)
-			return
-		}
+	return GzipResponse(nil)(next)
+}
 
-		w.Header().Set("Content-Encoding", "gzip")
-		gzWriter := gzip.NewWriter(w)
-		defer gzWriter.Close()
+// GzipResponse - сжатие ответов с передачей observe размеров ответа до и после сжатия.
+func GzipResponse(observe func(raw, compressed int)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-		gz := gzipResponseWriter{Writer: gzWriter, ResponseWriter: w}
-		fmt.Println("GzipResponseMiddleware")
-		next.ServeHTTP(gz, r)
-	})
+			w.Header().Set("Content-Encoding", "gzip")
+			compressed := &countingWriter{Writer: w}
+			gzWriter := gzip.NewWriter(compressed)
+			raw := &countingWriter{Writer: gzWriter}
+
+			gz := gzipResponseWriter{Writer: raw, ResponseWriter: w}
+			next.ServeHTTP(gz, r)
+
+			gzWriter.Close()
+			if observe != nil {
+				observe(raw.n, compressed.n)
+			}
+		})
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -28,6 +29,24 @@ func TestGzipMiddleware(t *testing.T) {
 		require.Equal(t, http.StatusOK, rec.Code)
 	})
 
+	t.Run("gzip response observer", func(t *testing.T) {
+		content := strings.Repeat("test response content ", 100)
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(content))
+		})
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		var raw, compressed int
+		rec := httptest.NewRecorder()
+		GzipResponse(func(r, c int) { raw, compressed = r, c })(handler).ServeHTTP(rec, req)
+
+		require.Equal(t, len(content), raw)
+		require.Equal(t, rec.Body.Len(), compressed)
+		require.Less(t, compressed, raw)
+	})
+
 	t.Run("gzip request decompression", func(t *testing.T) {
 		// Create gzipped content
 		var buf bytes.Buffer
@@ -1,5 +1,51 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Request struct {
-	URL string `json:"url" validate:"required,url"`
+	URL       string     `json:"url" validate:"required,url"`
+	Title     string     `json:"title,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+	Password  string     `json:"password,omitempty"`
+	MaxClicks int64      `json:"max_clicks,omitempty"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+}
+
+// BatchRequest - элемент пакетного запроса на сокращение.
+type BatchRequest struct {
+	CorrelationID string `json:"correlation_id"`
+	OriginalURL   string `json:"original_url"`
+}
+
+// APIKeyRequest - запрос на создание API-ключа.
+type APIKeyRequest struct {
+	Owner      string `json:"owner"`
+	Name       string `json:"name"`
+	DailyQuota int    `json:"daily_quota"`
+	TotalQuota int    `json:"total_quota"`
+}
+
+// OwnerRequest - запрос на смену владельца ссылки.
+type OwnerRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// URLPatch - частичное изменение ссылки владельцем. Отсутствующие поля не меняются,
+// expires_at: null снимает срок действия, not_before: null делает ссылку действующей сразу,
+// значение null в metadata удаляет ключ, пустой password снимает защиту паролем.
+type URLPatch struct {
+	OriginalURL  *string            `json:"original_url"`
+	RedirectCode *int               `json:"redirect_code"`
+	ExpiresAt    json.RawMessage    `json:"expires_at"`
+	NotBefore    json.RawMessage    `json:"not_before"`
+	Metadata     map[string]*string `json:"metadata"`
+	Title        *string            `json:"title"`
+	Tags         *[]string          `json:"tags"`
+	Notes        *string            `json:"notes"`
+	Password     *string            `json:"password"`
 }
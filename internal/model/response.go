@@ -1,5 +1,102 @@
 package model
 
+import "time"
+
 type Response struct {
 	Result string `json:"result"`
 }
+
+// BatchResponse - элемент ответа на пакетный запрос.
+type BatchResponse struct {
+	CorrelationID string `json:"correlation_id"`
+	ShortURL      string `json:"short_url"`
+}
+
+// UserURL - ссылка пользователя.
+type UserURL struct {
+	ShortURL    string   `json:"short_url"`
+	OriginalURL string   `json:"original_url"`
+	Title       string   `json:"title,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Notes       string   `json:"notes,omitempty"`
+	Protected   bool     `json:"protected,omitempty"`
+}
+
+// Stats - статистика сервиса.
+type Stats struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+}
+
+// Backup - созданная резервная копия.
+type Backup struct {
+	File string `json:"file"`
+}
+
+// APIKey - API-ключ. Key заполняется только в ответе на создание.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Key        string     `json:"key,omitempty"`
+	Owner      string     `json:"owner"`
+	Name       string     `json:"name,omitempty"`
+	DailyQuota int        `json:"daily_quota"`
+	TotalQuota int        `json:"total_quota"`
+	UsedTotal  int        `json:"used_total"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// AdminURL - ссылка в ответе административного API.
+type AdminURL struct {
+	Key         string `json:"key"`
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id,omitempty"`
+	Deleted     bool   `json:"deleted"`
+	Disabled    bool   `json:"disabled"`
+}
+
+// DeletedURLs - число удалённых ссылок.
+type DeletedURLs struct {
+	Deleted int `json:"deleted"`
+}
+
+// URLDetails - ссылка пользователя с версией и историей изменений.
+type URLDetails struct {
+	Key          string            `json:"key"`
+	ShortURL     string            `json:"short_url"`
+	OriginalURL  string            `json:"original_url"`
+	RedirectCode int               `json:"redirect_code"`
+	ExpiresAt    *time.Time        `json:"expires_at"`
+	NotBefore    *time.Time        `json:"not_before,omitempty"`
+	Status       string            `json:"status"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Title        string            `json:"title,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	Notes        string            `json:"notes,omitempty"`
+	Protected    bool              `json:"protected,omitempty"`
+	Clicks       int64             `json:"clicks"`
+	MaxClicks    int64             `json:"max_clicks,omitempty"`
+	Version      int               `json:"version"`
+	UpdatedAt    time.Time         `json:"updated_at,omitzero"`
+	History      []URLRevision     `json:"history,omitempty"`
+}
+
+// URLStats - статистика ссылки: переходы и окно действия.
+type URLStats struct {
+	Key       string     `json:"key"`
+	Status    string     `json:"status"`
+	Clicks    int64      `json:"clicks"`
+	MaxClicks int64      `json:"max_clicks,omitempty"`
+	NotBefore *time.Time `json:"not_before"`
+	NotAfter  *time.Time `json:"not_after"`
+}
+
+// URLRevision - предыдущая версия ссылки.
+type URLRevision struct {
+	Version      int        `json:"version"`
+	OriginalURL  string     `json:"original_url"`
+	RedirectCode int        `json:"redirect_code"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	ReplacedAt   time.Time  `json:"replaced_at"`
+}
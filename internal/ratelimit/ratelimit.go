@@ -0,0 +1,161 @@
+// Package ratelimit - ограничение частоты запросов по алгоритму token bucket.
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
+)
+
+// Limit - скорость пополнения корзины в запросах в секунду (больше нуля) и её ёмкость.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result - решение по запросу.
+type Result struct {
+	Allowed    bool
+	Limit      int           // ёмкость корзины
+	Remaining  int           // оставшиеся запросы
+	RetryAfter time.Duration // ожидание до следующего разрешённого запроса
+	Reset      time.Duration // ожидание до полной корзины
+}
+
+// bucket - корзина одного клиента.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter - набор корзин по ключам клиентов. Корзины, простоявшие дольше idle,
+// удаляются при очередной проверке, чтобы память не росла с числом клиентов.
+type Limiter struct {
+	limit Limit
+	idle  time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New - ограничитель с лимитом limit и временем жизни простаивающих корзин idle.
+func New(limit Limit, idle time.Duration) *Limiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &Limiter{
+		limit:   limit,
+		idle:    idle,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow - списание запроса из корзины key.
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	burst := float64(l.limit.Burst)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+	b.last = now
+
+	res := Result{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.wait(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = l.wait(burst - b.tokens)
+	return res
+}
+
+// Len - число корзин.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+// wait - время накопления tokens запросов.
+func (l *Limiter) wait(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.limit.Rate * float64(time.Second))
+}
+
+// sweep - удаление простаивающих корзин не чаще раза в idle.
+func (l *Limiter) sweep(now time.Time) {
+	if l.idle <= 0 || now.Sub(l.lastSweep) < l.idle {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware - ограничение запросов по ключу клиента keyFn с ответом 429 при превышении.
+func (l *Limiter) Middleware(keyFn func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := l.Allow(keyFn(r))
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientKey - ключ клиента: действующий API-ключ из Authorization: Bearer, иначе адрес клиента.
+// Ключ проверяется через valid, чтобы случайными ключами нельзя было получать новые корзины;
+// при nil valid API-ключи не учитываются. Сам API-ключ в памяти не хранится, только его хеш.
+func ClientKey(valid func(token string) bool) func(*http.Request) string {
+	return func(r *http.Request) string {
+		token, ok := auth.BearerToken(r)
+		if ok && valid != nil && valid(token) {
+			sum := sha256.Sum256([]byte(token))
+			return "key:" + hex.EncodeToString(sum[:16])
+		}
+		return "ip:" + clientip.FromRequest(r)
+	}
+}
+
+// seconds - длительность в целых секундах с округлением вверх.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimiter(limit Limit, idle time.Duration) (*Limiter, *time.Time) {
+	l := New(limit, idle)
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return clock }
+	return l, &clock
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("burst and refill", func(t *testing.T) {
+		l, clock := newLimiter(Limit{Rate: 2, Burst: 3}, 0)
+
+		for i := 2; i >= 0; i-- {
+			res := l.Allow("a")
+			require.True(t, res.Allowed)
+			require.Equal(t, i, res.Remaining)
+		}
+
+		res := l.Allow("a")
+		require.False(t, res.Allowed)
+		require.Equal(t, 500*time.Millisecond, res.RetryAfter)
+		require.Equal(t, 1500*time.Millisecond, res.Reset)
+
+		// Другой клиент не затронут
+		require.True(t, l.Allow("b").Allowed)
+
+		*clock = clock.Add(500 * time.Millisecond)
+		require.True(t, l.Allow("a").Allowed)
+		require.False(t, l.Allow("a").Allowed)
+	})
+
+	t.Run("idle buckets expire", func(t *testing.T) {
+		l, clock := newLimiter(Limit{Rate: 1, Burst: 1}, time.Minute)
+
+		l.Allow("a")
+		l.Allow("b")
+		require.Equal(t, 2, l.Len())
+
+		*clock = clock.Add(30 * time.Second)
+		l.Allow("b")
+		*clock = clock.Add(40 * time.Second)
+		l.Allow("c")
+		require.Equal(t, 2, l.Len()) // a удалён, b активен
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	l, _ := newLimiter(Limit{Rate: 1, Burst: 1}, 0)
+	resolver, err := clientip.New(nil)
+	require.NoError(t, err)
+
+	handler := resolver.Middleware(l.Middleware(ClientKey(func(token string) bool { return token == "secret" }))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})))
+
+	send := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("203.0.113.5:1000", "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	require.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	require.Equal(t, "1", w.Header().Get("X-RateLimit-Reset"))
+
+	w = send("203.0.113.5:2000", "")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// API-ключ - отдельная корзина
+	require.Equal(t, http.StatusCreated, send("203.0.113.5:3000", "secret").Code)
+	require.Equal(t, http.StatusTooManyRequests, send("198.51.100.1:3000", "secret").Code)
+
+	// Схема Bearer без учёта регистра, как при аутентификации
+	req := httptest.NewRequest("POST", "/", nil)
+	req.RemoteAddr = "192.0.2.7:3000"
+	req.Header.Set("Authorization", "bearer secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Недействительный ключ не даёт новой корзины
+	require.Equal(t, http.StatusTooManyRequests, send("203.0.113.5:4000", "random").Code)
+}
@@ -0,0 +1,179 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/accesslog"
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/handler"
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+	"github.com/ParkhomenkoDV/URLShortener/internal/metrics"
+	"github.com/ParkhomenkoDV/URLShortener/internal/middleware"
+	"github.com/ParkhomenkoDV/URLShortener/internal/ratelimit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/ParkhomenkoDV/URLShortener/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// options - необязательные зависимости маршрутизатора.
+type options struct {
+	metrics   *metrics.Metrics
+	accessLog *accesslog.Logger
+	keys      *apikey.Store
+	audit     *audit.Log
+	pending   []byte
+}
+
+// Option - настройка маршрутизатора.
+type Option func(*options)
+
+// WithMetrics - учёт запросов и сжатия ответов в метриках m.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// WithAccessLog - запись запросов в журнал l.
+func WithAccessLog(l *accesslog.Logger) Option {
+	return func(o *options) {
+		o.accessLog = l
+	}
+}
+
+// WithAPIKeys - аутентификация по API-ключам keys и управление ими через /api/admin/keys.
+func WithAPIKeys(keys *apikey.Store) Option {
+	return func(o *options) {
+		o.keys = keys
+	}
+}
+
+// WithAudit - запись действий со ссылками и ключами в журнал аудита log и его просмотр через /api/admin/audit.
+func WithAudit(log *audit.Log) Option {
+	return func(o *options) {
+		o.audit = log
+	}
+}
+
+// WithPendingPage - страница page для ссылок, срок действия которых ещё не начался.
+func WithPendingPage(page []byte) Option {
+	return func(o *options) {
+		o.pending = page
+	}
+}
+
+// New - создание маршрутизатора со всеми обработчиками сервиса.
+func New(cfg *config.Config, db storage.Store, opts ...Option) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	handlerOpts := []handler.Option{handler.WithAudit(o.audit), handler.WithPendingPage(o.pending)}
+	authenticator := auth.New(cfg.SecretKey)
+	if o.keys != nil {
+		handlerOpts = append(handlerOpts, handler.WithAPIKeys(o.keys))
+		authenticator.WithAPIKeys(o.keys)
+	}
+	hand := handler.New(cfg, db, handlerOpts...)
+
+	gzipResponse := middleware.GzipResponseMiddleware
+	// Список прокси проверен при загрузке конфигурации
+	resolver, _ := clientip.New(clientip.ParseList(cfg.TrustedProxies))
+
+	r := chi.NewRouter()
+	r.Use(resolver.Middleware)
+	r.Use(tracing.Middleware)
+	if o.metrics != nil {
+		r.Use(o.metrics.Middleware)
+		gzipResponse = middleware.GzipResponse(o.metrics.ObserveGzip)
+	}
+	r.Use(logger.RequestIDMiddleware)
+	if o.accessLog != nil {
+		r.Use(tracing.Wrap("access_log", o.accessLog.Middleware))
+	}
+	r.Use(tracing.Wrap("gzip_request", middleware.GzipRequestMiddleware))
+	r.Use(tracing.Wrap("gzip_response", gzipResponse))
+	r.Use(tracing.Wrap("recoverer", chiMiddleware.Recoverer))
+	r.Use(tracing.Wrap("logging", logger.LoggingMiddleware))
+
+	var validKey func(string) bool
+	if o.keys != nil {
+		validKey = func(token string) bool {
+			_, err := o.keys.Authenticate(token)
+			return err == nil
+		}
+	}
+	clientKey := ratelimit.ClientKey(validKey)
+	redirectLimit := rateLimit("redirect_limit", cfg.RateLimitRedirect, cfg.RateLimitRedirectBurst, cfg.RateLimitIdle, clientKey)
+	writeLimit := rateLimit("write_limit", cfg.RateLimitWrite, cfg.RateLimitWriteBurst, cfg.RateLimitIdle, clientKey)
+
+	r.With(redirectLimit).Get("/{id}", hand.Get)
+	r.With(redirectLimit).Post("/{id}", hand.Unlock)
+	r.Route("/api/internal", func(r chi.Router) {
+		r.Use(tracing.Wrap("admin_auth", auth.Admin(cfg.AdminToken)))
+
+		r.Get("/stats", hand.GetStats)
+		r.Post("/backup", hand.PostBackup)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(writeLimit)
+		r.Use(tracing.Wrap("auth", authenticator.Middleware))
+
+		r.Post("/", hand.Post)
+		r.Post("/api/shorten", hand.PostJSON)
+		r.Post("/api/shorten/batch", hand.PostBatch)
+		r.Delete("/api/user/urls", hand.DeleteUserURLs)
+		r.Patch("/api/urls/{id}", hand.PatchURL)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(tracing.Wrap("auth", authenticator.Middleware))
+
+		r.Get("/api/user/urls", hand.GetUserURLs)
+		r.Get("/api/urls/{id}", hand.GetURL)
+		r.Get("/api/urls/{id}/stats", hand.GetURLStats)
+	})
+
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(tracing.Wrap("admin_auth", auth.Admin(cfg.AdminToken)))
+
+		r.Get("/urls", hand.SearchURLs)
+		r.Post("/urls/{id}/disable", hand.DisableURL)
+		r.Post("/urls/{id}/enable", hand.EnableURL)
+		r.Put("/urls/{id}/owner", hand.ReassignURL)
+		r.Delete("/users/{userID}/urls", hand.DeleteUserURLsAdmin)
+
+		if o.audit != nil {
+			r.Get("/audit", hand.GetAudit)
+			r.Get("/audit/export", hand.ExportAudit)
+		}
+		if o.keys != nil {
+			r.Post("/keys", hand.CreateAPIKey)
+			r.Get("/keys", hand.ListAPIKeys)
+			r.Delete("/keys/{id}", hand.RevokeAPIKey)
+		}
+	})
+
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	return r
+}
+
+// rateLimit - ограничение rate запросов в секунду на клиента, при нулевом rate - без ограничения.
+func rateLimit(name string, rate float64, burst int, idle time.Duration, key func(*http.Request) string) func(http.Handler) http.Handler {
+	if rate <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	limiter := ratelimit.New(ratelimit.Limit{Rate: rate, Burst: burst}, idle)
+	return tracing.Wrap(name, limiter.Middleware(key))
+}
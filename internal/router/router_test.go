@@ -0,0 +1,267 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/apikey"
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/auth"
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+	"github.com/ParkhomenkoDV/URLShortener/internal/metrics"
+	"github.com/ParkhomenkoDV/URLShortener/internal/model"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter(t *testing.T) {
+	require.NoError(t, logger.New(logger.Config{Level: "error"}))
+	cfg := &config.Config{BaseURL: "http://localhost:8080"}
+	r := New(cfg, storage.New())
+
+	t.Run("shorten issues auth cookie", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("https://example.com")))
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.Equal(t, auth.CookieName, cookies[0].Name)
+	})
+
+	t.Run("patch link", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("https://example.org")))
+		require.Equal(t, http.StatusCreated, w.Code)
+		cookie := w.Result().Cookies()[0]
+		key := strings.TrimPrefix(w.Body.String(), "http://localhost:8080/")
+
+		req := httptest.NewRequest("GET", "/api/urls/"+key, nil)
+		req.AddCookie(cookie)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+
+		req = httptest.NewRequest("PATCH", "/api/urls/"+key, strings.NewReader(`{"redirect_code":302}`))
+		req.Header.Set("If-Match", etag)
+		req.AddCookie(cookie)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NotEqual(t, etag, w.Header().Get("ETag"))
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/"+key, nil))
+		require.Equal(t, http.StatusFound, w.Code)
+	})
+
+	t.Run("password protected link", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/shorten", strings.NewReader(`{"url":"https://docs.example","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var resp model.Response
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		path := strings.TrimPrefix(resp.Result, "http://localhost:8080")
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		req = httptest.NewRequest("POST", path, strings.NewReader("password=secret"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusSeeOther, w.Code)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+
+		req = httptest.NewRequest("GET", path, nil)
+		req.AddCookie(cookies[0])
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		require.Equal(t, "https://docs.example", w.Header().Get("Location"))
+	})
+
+	t.Run("request id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/unknown", nil))
+		require.NotEmpty(t, w.Header().Get(logger.RequestIDHeader))
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("PUT", "/api/shorten", nil))
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func TestRouterMetrics(t *testing.T) {
+	require.NoError(t, logger.New(logger.Config{Level: "error"}))
+	m := metrics.New()
+	r := New(&config.Config{BaseURL: "http://localhost:8080"}, storage.New(), WithMetrics(m))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/unknown", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Contains(t, w.Body.String(), `shortener_http_requests_total{method="GET",route="/{id}",status="404"} 1`)
+}
+
+func TestRouterRateLimit(t *testing.T) {
+	cfg := &config.Config{BaseURL: "http://localhost:8080", RateLimitWrite: 1, RateLimitWriteBurst: 1, RateLimitRedirect: 1, RateLimitRedirectBurst: 2}
+	r := New(cfg, storage.New())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("https://example.com")))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("https://example.org")))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Переходы ограничиваются отдельно от записи
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestRouterAPIKeys(t *testing.T) {
+	keys, err := apikey.Open(filepath.Join(t.TempDir(), "apikeys.json"))
+	require.NoError(t, err)
+	cfg := &config.Config{BaseURL: "http://localhost:8080", AdminToken: "admin"}
+	r := New(cfg, storage.New(), WithAPIKeys(keys))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/api/admin/keys", strings.NewReader(`{"owner":"service"}`)))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest("POST", "/api/admin/keys", strings.NewReader(`{"owner":"service","daily_quota":1}`))
+	req.Header.Set("Authorization", "Bearer admin")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created model.APIKey
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+
+	shorten := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(url))
+		req.Header.Set("Authorization", "Bearer "+created.Key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w = shorten("https://example.com")
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Empty(t, w.Result().Cookies())
+	require.Equal(t, "0", w.Header().Get("X-Quota-Daily-Remaining"))
+
+	w = shorten("https://example.org")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.NotEmpty(t, w.Header().Get("X-Quota-Daily-Reset"))
+
+	req = httptest.NewRequest("GET", "/api/user/urls", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Key)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "https://example.com")
+}
+
+func TestRouterAdmin(t *testing.T) {
+	db := storage.New()
+	db.Set("key", "https://example.com")
+
+	t.Run("disabled without token", func(t *testing.T) {
+		r := New(&config.Config{BaseURL: "http://localhost:8080"}, db)
+
+		req := httptest.NewRequest("POST", "/api/admin/urls/key/disable", nil)
+		req.Header.Set("Authorization", "Bearer ")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("disable link", func(t *testing.T) {
+		r := New(&config.Config{BaseURL: "http://localhost:8080", AdminToken: "admin"}, db)
+
+		req := httptest.NewRequest("POST", "/api/admin/urls/key/disable", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/key", nil))
+		require.Equal(t, http.StatusUnavailableForLegalReasons, w.Code)
+
+		req = httptest.NewRequest("GET", "/api/admin/urls?key=ke", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"disabled":true`)
+	})
+
+	t.Run("backup requires token", func(t *testing.T) {
+		r := New(&config.Config{BaseURL: "http://localhost:8080", AdminToken: "admin"}, db)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/api/internal/backup", nil))
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		req := httptest.NewRequest("POST", "/api/internal/backup", nil)
+		req.Header.Set("Authorization", "Bearer wrong")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		r = New(&config.Config{BaseURL: "http://localhost:8080"}, db)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/api/internal/backup", nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		log, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+		require.NoError(t, err)
+		defer log.Close()
+		r := New(&config.Config{BaseURL: "http://localhost:8080", AdminToken: "admin"}, db, WithAudit(log))
+
+		req := httptest.NewRequest("POST", "/api/admin/urls/key/enable", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		req = httptest.NewRequest("GET", "/api/admin/audit/export?actor=admin", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var ev audit.Event
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ev))
+		require.Equal(t, audit.ActionLinkEnable, ev.Action)
+		require.Equal(t, "key", ev.Key)
+		// Прокси не доверенный, заголовок игнорируется
+		require.Equal(t, "192.0.2.1", ev.IP)
+	})
+}
@@ -2,49 +2,87 @@ package server
 
 import (
 	"context"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/ParkhomenkoDV/URLShortener/internal/backup"
 	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
 	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
 )
 
 type Server struct {
 	config     *config.Config
 	httpServer *http.Server
-	db         *storage.DB
+	db         storage.Store
+
+	adminServer *http.Server // служебный listener, nil - отключён
+
+	stopAutosave chan struct{}
+	autosaveDone chan struct{}
+
+	backups     *backup.Manager
+	stopBackups chan struct{}
+	backupsDone chan struct{}
 }
 
 // New - создание нового сервера.
-func New(config *config.Config, handler http.Handler, db *storage.DB) *Server {
+func New(config *config.Config, handler http.Handler, db storage.Store) *Server {
 	return &Server{
 		config: config,
 		httpServer: &http.Server{
 			Addr:    config.ServerAddress,
 			Handler: handler,
 		},
-		db: db,
+		db:           db,
+		stopAutosave: make(chan struct{}),
+		autosaveDone: make(chan struct{}),
+		backups:      backup.New(db, config.FileStorage, config.BackupDir, config.BackupKeep, config.BackupMaxAge),
+		stopBackups:  make(chan struct{}),
+		backupsDone:  make(chan struct{}),
 	}
 }
 
+// WithAdmin - служебный listener на config.AdminAddress, если адрес задан.
+func (s *Server) WithAdmin(handler http.Handler) *Server {
+	if s.config.AdminAddress != "" {
+		s.adminServer = &http.Server{
+			Addr:    s.config.AdminAddress,
+			Handler: handler,
+		}
+	}
+	return s
+}
+
 // Start - запуск сервера.
 func (s *Server) Start() error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		log.Printf("Server started at %s \n", s.httpServer.Addr)
+		logger.L().Infow("server started", "addr", s.httpServer.Addr)
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("error starting server: %v", err)
+			logger.L().Fatalw("error starting server", "error", err)
 		}
 	}()
 
+	if s.adminServer != nil {
+		go func() {
+			logger.L().Infow("admin server started", "addr", s.adminServer.Addr)
+			if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.L().Fatalw("error starting admin server", "error", err)
+			}
+		}()
+	}
+
+	go s.autosave()
+	go s.backup()
+
 	<-quit // Ожидаем сигнал завершения
-	log.Println("Server ended")
+	logger.L().Info("server stopping")
 
 	return s.shutdown()
 }
@@ -57,22 +95,88 @@ func (s *Server) shutdown() error {
 
 	// Завершение работы HTTP сервера
 	if err := s.httpServer.Shutdown(ctx); err != nil {
-		log.Printf("error starting server: %v", err)
+		logger.L().Errorw("error stopping server", "error", err)
 		return err
 	}
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			logger.L().Errorw("error stopping admin server", "error", err)
+		}
+	}
+
+	// Останавливаем автосохранение и копии, чтобы они не пересеклись с финальным сохранением
+	close(s.stopAutosave)
+	<-s.autosaveDone
+	close(s.stopBackups)
+	<-s.backupsDone
+
+	// Сохраняем данные перед завершением. Бэкенд kv сохраняет каждую запись сам
+	if s.config.Backend != config.BackendKV {
+		if err := s.saveData(); err != nil {
+			return err
+		}
+	}
 
-	// Сохраняем данные перед завершением
-	return s.saveData()
+	return s.db.Close()
+}
+
+// autosave - периодическое сохранение данных, если задан интервал.
+func (s *Server) autosave() {
+	defer close(s.autosaveDone)
+
+	if s.config.AutosaveInterval <= 0 || s.config.Backend == config.BackendKV {
+		<-s.stopAutosave
+		return
+	}
+
+	ticker := time.NewTicker(s.config.AutosaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopAutosave:
+			return
+		case <-ticker.C:
+			s.saveData()
+		}
+	}
+}
+
+// backup - периодические резервные копии с удалением устаревших, если задан интервал.
+func (s *Server) backup() {
+	defer close(s.backupsDone)
+
+	if s.config.BackupInterval <= 0 {
+		<-s.stopBackups
+		return
+	}
+
+	ticker := time.NewTicker(s.config.BackupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopBackups:
+			return
+		case <-ticker.C:
+			path, err := s.backups.Snapshot()
+			if err != nil {
+				logger.L().Errorw("backup failed", "error", err)
+				continue
+			}
+			logger.L().Infow("backup created", "file", path)
+		}
+	}
 }
 
 // Сохраняет данные в файл
 func (s *Server) saveData() error {
-	log.Println("Saving data")
+	logger.L().Infow("saving data", "file", s.config.FileStorage)
 	if err := s.db.SaveToFile(s.config.FileStorage); err != nil {
-		log.Printf("saving data error: %v", err)
+		logger.L().Errorw("saving data failed", "file", s.config.FileStorage, "error", err)
 		return err
 	}
 
-	log.Println("Data saved successfully")
+	logger.L().Info("data saved")
 	return nil
 }
@@ -3,7 +3,9 @@ package server
 import (
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/ParkhomenkoDV/URLShortener/internal/config"
 	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
@@ -25,6 +27,18 @@ func TestServer(t *testing.T) {
 		require.Equal(t, db, server.db)
 	})
 
+	t.Run("admin listener", func(t *testing.T) {
+		db := storage.New()
+
+		server := New(&config.Config{}, http.NewServeMux(), db).WithAdmin(http.NewServeMux())
+		require.Nil(t, server.adminServer)
+
+		cfg := &config.Config{AdminAddress: "localhost:9090"}
+		server = New(cfg, http.NewServeMux(), db).WithAdmin(http.NewServeMux())
+		require.NotNil(t, server.adminServer)
+		require.Equal(t, "localhost:9090", server.adminServer.Addr)
+	})
+
 	t.Run("save data", func(t *testing.T) {
 		tempFile := "/tmp/test_save.json"
 		defer os.Remove(tempFile)
@@ -42,4 +56,50 @@ func TestServer(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("autosave", func(t *testing.T) {
+		tempFile := filepath.Join(t.TempDir(), "db.json")
+
+		cfg := &config.Config{FileStorage: tempFile, AutosaveInterval: 10 * time.Millisecond}
+		db := storage.New()
+		db.Set("testKey", "https://example.com")
+
+		server := New(cfg, http.NewServeMux(), db)
+		go server.autosave()
+
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(tempFile)
+			return err == nil
+		}, time.Second, 5*time.Millisecond)
+
+		close(server.stopAutosave)
+		<-server.autosaveDone
+	})
+
+	t.Run("periodic backups", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := &config.Config{
+			FileStorage:    filepath.Join(dir, "db.json"),
+			BackupDir:      filepath.Join(dir, "backups"),
+			BackupInterval: 10 * time.Millisecond,
+			BackupKeep:     2,
+		}
+		db := storage.New()
+		db.Set("testKey", "https://example.com")
+
+		server := New(cfg, http.NewServeMux(), db)
+		go server.backup()
+
+		require.Eventually(t, func() bool {
+			backups, err := server.backups.List()
+			return err == nil && len(backups) == 2
+		}, time.Second, 5*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+
+		close(server.stopBackups)
+		<-server.backupsDone
+
+		backups, err := server.backups.List()
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+	})
 }
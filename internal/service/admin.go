@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/ParkhomenkoDV/URLShortener/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Search - страница ссылок, включая удалённые, для модерации.
+func (s *Shortener) Search(ctx context.Context, q storage.Query) (storage.Page, error) {
+	q.Now = s.now()
+	return s.repo.Search(ctx, q)
+}
+
+// SetDisabled - отключение или повторное включение ссылки модератором.
+// Возвращает состояние записи до и после изменения.
+func (s *Shortener) SetDisabled(ctx context.Context, key string, disabled bool) (before, after storage.Record, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.SetDisabled", trace.WithAttributes(
+		attribute.String("shortener.key", key), attribute.Bool("shortener.disabled", disabled)))
+	defer func() { endSpan(span, err) }()
+
+	action := audit.ActionLinkEnable
+	if disabled {
+		action = audit.ActionLinkDisable
+	}
+	return s.update(ctx, action, key, func(rec *storage.Record) error {
+		rec.Disabled = disabled
+		return nil
+	})
+}
+
+// Reassign - передача ссылки другому владельцу.
+// Возвращает состояние записи до и после изменения.
+func (s *Shortener) Reassign(ctx context.Context, key, owner string) (before, after storage.Record, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.Reassign", trace.WithAttributes(attribute.String("shortener.key", key)))
+	defer func() { endSpan(span, err) }()
+
+	return s.update(ctx, audit.ActionLinkReassign, key, func(rec *storage.Record) error {
+		rec.UserID = owner
+		return nil
+	})
+}
+
+// DeleteUserURLs - удаление всех действующих ссылок пользователя.
+// Возвращает записи в состоянии до удаления.
+func (s *Shortener) DeleteUserURLs(ctx context.Context, userID string) (_ []storage.Record, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.DeleteUserURLs")
+	defer func() { endSpan(span, err) }()
+
+	records, err := s.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]storage.Record, 0, len(records))
+	for _, rec := range records {
+		before, _, err := s.update(ctx, audit.ActionLinkDelete, rec.ShortURL, func(rec *storage.Record) error {
+			// Ссылка могла быть удалена после FindByUser
+			if rec.DeletedFlag {
+				return ErrGone
+			}
+			rec.DeletedFlag = true
+			return nil
+		})
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrGone) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, before)
+	}
+	span.SetAttributes(attribute.Int("shortener.deleted", len(deleted)))
+	return deleted, nil
+}
+
+// update - изменение записи администратором с записью action в журнал аудита.
+// Версия записи увеличивается, чтобы владелец с устаревшим If-Match получил ErrPrecondition.
+// Ошибка fn отменяет изменение. Возвращает состояние до и после.
+func (s *Shortener) update(ctx context.Context, action, key string, fn func(rec *storage.Record) error) (before, after storage.Record, err error) {
+	after, err = s.repo.Update(ctx, key, func(rec *storage.Record) error {
+		before = *rec
+		if err := fn(rec); err != nil {
+			return err
+		}
+		rec.Version++
+		rec.UpdatedAt = s.now().UTC()
+		return nil
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.Record{}, storage.Record{}, ErrNotFound
+	}
+	if err != nil {
+		return storage.Record{}, storage.Record{}, err
+	}
+
+	s.record(ctx, audit.ActorAdmin, action, &before, &after)
+	return before, after, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// racingRepo - хранилище, в котором ссылки удаляются сразу после FindByUser.
+type racingRepo struct {
+	*storage.DB
+}
+
+func (r racingRepo) FindByUser(ctx context.Context, userID string) ([]storage.Record, error) {
+	records, err := r.DB.FindByUser(ctx, userID)
+	for _, rec := range records {
+		r.DB.MarkDeleted(ctx, rec.ShortURL)
+	}
+	return records, err
+}
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+	db := storage.New()
+	s := New(db, "http://localhost:8080")
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "a", OriginalURL: "https://example.com", UserID: "user1"}))
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "b", OriginalURL: "https://google.com", UserID: "user1"}))
+
+	t.Run("disable and enable", func(t *testing.T) {
+		before, after, err := s.SetDisabled(ctx, "a", true)
+		require.NoError(t, err)
+		require.False(t, before.Disabled)
+		require.True(t, after.Disabled)
+
+		_, err = s.Resolve(ctx, "a")
+		require.ErrorIs(t, err, ErrDisabled)
+
+		// Отключённая ссылка по-прежнему занимает свой URL
+		_, err = s.Shorten(ctx, "user2", "https://example.com")
+		require.ErrorIs(t, err, ErrConflict)
+
+		_, _, err = s.SetDisabled(ctx, "a", false)
+		require.NoError(t, err)
+		originalURL, err := s.Resolve(ctx, "a")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com", originalURL)
+
+		_, _, err = s.SetDisabled(ctx, "unknown", true)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("reassign", func(t *testing.T) {
+		before, after, err := s.Reassign(ctx, "a", "user2")
+		require.NoError(t, err)
+		require.Equal(t, "user1", before.UserID)
+		require.Equal(t, "user2", after.UserID)
+
+		require.ErrorIs(t, s.Delete(ctx, "user1", "a"), ErrForbidden)
+		records, err := s.UserURLs(ctx, "user2")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("search and delete user URLs", func(t *testing.T) {
+		page, err := s.Search(ctx, storage.Query{Owner: "user1"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+
+		deleted, err := s.DeleteUserURLs(ctx, "user1")
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		require.Equal(t, "b", deleted[0].ShortURL)
+
+		_, err = s.Resolve(ctx, "b")
+		require.ErrorIs(t, err, ErrGone)
+
+		deleted, err = s.DeleteUserURLs(ctx, "user1")
+		require.NoError(t, err)
+		require.Empty(t, deleted)
+	})
+
+	t.Run("delete user URLs deleted concurrently", func(t *testing.T) {
+		db := storage.New()
+		require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "c", OriginalURL: "https://example.org", UserID: "user3", Version: 1}))
+		s := New(racingRepo{DB: db}, "http://localhost:8080")
+
+		deleted, err := s.DeleteUserURLs(ctx, "user3")
+		require.NoError(t, err)
+		require.Empty(t, deleted)
+
+		rec, err := db.Find(ctx, "c")
+		require.NoError(t, err)
+		require.Equal(t, 1, rec.Version)
+	})
+}
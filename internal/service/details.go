@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// Ограничения описания ссылки.
+const (
+	MaxTitleLength = 200
+	MaxNotesLength = 2000
+	MaxTags        = 20
+	MaxTagLength   = 50
+)
+
+// Details - описание ссылки и условия перехода по ней, задаваемые владельцем.
+type Details struct {
+	Title     string
+	Tags      []string
+	Notes     string
+	Password  string    // пароль доступа, пустой - ссылка открыта
+	MaxClicks int64     // допустимое число переходов, 0 - без ограничения
+	NotBefore time.Time // начало действия, нулевое - сразу
+	NotAfter  time.Time // окончание действия, нулевое - бессрочно
+}
+
+// validWindow - окончание действия ссылки позже начала, если заданы оба.
+func validWindow(notBefore, notAfter time.Time) bool {
+	return notBefore.IsZero() || notAfter.IsZero() || notAfter.After(notBefore)
+}
+
+// normalize - проверка описания и приведение меток к нижнему регистру без повторов.
+func (d Details) normalize() (Details, error) {
+	d.Title = strings.TrimSpace(d.Title)
+	if utf8.RuneCountInString(d.Title) > MaxTitleLength || utf8.RuneCountInString(d.Notes) > MaxNotesLength {
+		return Details{}, ErrInvalidDetails
+	}
+
+	tags, err := normalizeTags(d.Tags)
+	if err != nil {
+		return Details{}, err
+	}
+	d.Tags = tags
+	return d, nil
+}
+
+// normalizeTags - метки в нижнем регистре без пробелов по краям, без пустых и повторов, по алфавиту.
+func normalizeTags(raw []string) ([]string, error) {
+	var tags []string
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, ErrInvalidDetails
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > MaxTags {
+		return nil, ErrInvalidDetails
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
+
+// SearchUserURLs - страница ссылок пользователя по условиям q.
+// Без условия на состояние удалённые ссылки не выдаются.
+func (s *Shortener) SearchUserURLs(ctx context.Context, userID string, q storage.Query) (storage.Page, error) {
+	q.Owner = userID
+	q.Tag = strings.ToLower(strings.TrimSpace(q.Tag))
+	if len(q.Status) == 0 {
+		q.Status = []string{storage.StatusActive, storage.StatusScheduled, storage.StatusExpired}
+	}
+	q.Now = s.now()
+	return s.repo.Search(ctx, q)
+}
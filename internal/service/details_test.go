@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetails(t *testing.T) {
+	ctx := context.Background()
+	db := storage.New()
+	s := New(db, "http://localhost:8080")
+
+	shortURL, err := s.ShortenWithDetails(ctx, "user1", "https://shop.example/summer", Details{
+		Title: "  Summer sale ",
+		Tags:  []string{"Promo", "summer", " promo ", ""},
+		Notes: "Banner on the main page",
+	})
+	require.NoError(t, err)
+	key := strings.TrimPrefix(shortURL, "http://localhost:8080/")
+
+	rec, err := db.Find(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, "Summer sale", rec.Title)
+	require.Equal(t, []string{"promo", "summer"}, rec.Tags)
+	require.Equal(t, "Banner on the main page", rec.Notes)
+
+	_, err = s.Shorten(ctx, "user1", "https://shop.example/winter")
+	require.NoError(t, err)
+
+	t.Run("search", func(t *testing.T) {
+		page, err := s.SearchUserURLs(ctx, "user1", storage.Query{Tag: "PROMO"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, key, page.Records[0].ShortURL)
+
+		page, err = s.SearchUserURLs(ctx, "user1", storage.Query{Text: "shop"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 2)
+
+		page, err = s.SearchUserURLs(ctx, "user2", storage.Query{Text: "shop"})
+		require.NoError(t, err)
+		require.Empty(t, page.Records)
+	})
+
+	t.Run("sort by clicks", func(t *testing.T) {
+		_, _, err := s.Redirect(ctx, key)
+		require.NoError(t, err)
+
+		page, err := s.SearchUserURLs(ctx, "user1", storage.Query{Sort: storage.SortClicks, Desc: true, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, key, page.Records[0].ShortURL)
+		require.Equal(t, int64(1), page.Records[0].Clicks)
+		require.False(t, page.Records[0].CreatedAt.IsZero())
+		require.NotNil(t, page.Next)
+
+		page, err = s.SearchUserURLs(ctx, "user1", storage.Query{Sort: storage.SortClicks, Desc: true, After: page.Next})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, int64(0), page.Records[0].Clicks)
+		require.Nil(t, page.Next)
+	})
+
+	t.Run("max clicks", func(t *testing.T) {
+		shortURL, err := s.ShortenWithDetails(ctx, "user1", "https://files.example/invite", Details{MaxClicks: 2})
+		require.NoError(t, err)
+		key := strings.TrimPrefix(shortURL, "http://localhost:8080/")
+
+		for range 2 {
+			_, _, err := s.Redirect(ctx, key)
+			require.NoError(t, err)
+		}
+		_, _, err = s.Redirect(ctx, key)
+		require.ErrorIs(t, err, ErrExhausted)
+
+		rec, err := db.Find(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), rec.Clicks)
+
+		_, err = s.ShortenWithDetails(ctx, "user1", "https://files.example/other", Details{MaxClicks: -1})
+		require.ErrorIs(t, err, ErrInvalidMaxClicks)
+	})
+
+	t.Run("activation window", func(t *testing.T) {
+		now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		s := New(db, "http://localhost:8080", WithClock(func() time.Time { return now }))
+
+		shortURL, err := s.ShortenWithDetails(ctx, "user1", "https://shop.example/launch", Details{
+			NotBefore: now.Add(time.Hour),
+			NotAfter:  now.Add(2 * time.Hour),
+		})
+		require.NoError(t, err)
+		key := strings.TrimPrefix(shortURL, "http://localhost:8080/")
+
+		rec, err := s.Link(ctx, "user1", key)
+		require.NoError(t, err)
+		require.Equal(t, storage.StatusScheduled, s.Status(rec))
+		_, _, err = s.Redirect(ctx, key)
+		require.ErrorIs(t, err, ErrNotActive)
+
+		now = now.Add(time.Hour)
+		_, _, err = s.Redirect(ctx, key)
+		require.NoError(t, err)
+
+		now = now.Add(time.Hour)
+		_, _, err = s.Redirect(ctx, key)
+		require.ErrorIs(t, err, ErrExpired)
+
+		_, err = s.ShortenWithDetails(ctx, "user1", "https://shop.example/backwards", Details{NotBefore: now, NotAfter: now})
+		require.ErrorIs(t, err, ErrInvalidWindow)
+
+		notBefore := now.Add(3 * time.Hour)
+		_, err = s.UpdateLink(ctx, "user1", key, Patch{NotBefore: &notBefore})
+		require.ErrorIs(t, err, ErrInvalidWindow)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		title := "Autumn sale"
+		tags := []string{"autumn"}
+		rec, err := s.UpdateLink(ctx, "user1", key, Patch{Title: &title, Tags: &tags})
+		require.NoError(t, err)
+		require.Equal(t, "Autumn sale", rec.Title)
+		require.Equal(t, []string{"autumn"}, rec.Tags)
+		require.Equal(t, "Banner on the main page", rec.Notes)
+
+		page, err := s.SearchUserURLs(ctx, "user1", storage.Query{Text: "autumn"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		_, err := s.ShortenWithDetails(ctx, "user1", "https://example.com", Details{Title: strings.Repeat("a", MaxTitleLength+1)})
+		require.ErrorIs(t, err, ErrInvalidDetails)
+
+		tags := make([]string, MaxTags+1)
+		for i := range tags {
+			tags[i] = strings.Repeat("t", i+1)
+		}
+		_, err = s.UpdateLink(ctx, "user1", key, Patch{Tags: &tags})
+		require.ErrorIs(t, err, ErrInvalidDetails)
+	})
+}
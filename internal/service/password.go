@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/ratelimit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/ParkhomenkoDV/URLShortener/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordLength - максимальная длина пароля ссылки в байтах, ограничение bcrypt.
+const MaxPasswordLength = 72
+
+// DefaultAccessTTL - время жизни пропуска к защищённой ссылке по умолчанию.
+const DefaultAccessTTL = 15 * time.Minute
+
+// DefaultPasswordAttempts - попытки ввода пароля по умолчанию: 5 подряд, затем одна в минуту.
+var DefaultPasswordAttempts = ratelimit.Limit{Rate: 1.0 / 60, Burst: 5}
+
+// Access - пропуск к защищённой паролем ссылке.
+type Access struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// WithLinkAccess - подпись пропусков к защищённым ссылкам ключом secret с временем жизни ttl.
+// При пустом ключе генерируется случайный, при нулевом ttl используется DefaultAccessTTL.
+func WithLinkAccess(secret string, ttl time.Duration) Option {
+	return func(s *Shortener) {
+		s.accessSecret = []byte(secret)
+		if ttl > 0 {
+			s.accessTTL = ttl
+		}
+	}
+}
+
+// WithPasswordAttempts - ограничение попыток ввода пароля для каждой ссылки.
+// Нулевая скорость или ёмкость отключает ограничение.
+func WithPasswordAttempts(limit ratelimit.Limit) Option {
+	return func(s *Shortener) {
+		s.attempts = nil
+		if limit.Rate > 0 && limit.Burst > 0 {
+			s.attempts = newAttemptsLimiter(limit)
+		}
+	}
+}
+
+// newAttemptsLimiter - ограничитель попыток. Корзина удаляется, когда успевает наполниться.
+func newAttemptsLimiter(limit ratelimit.Limit) *ratelimit.Limiter {
+	refill := time.Duration(float64(max(limit.Burst, 1)) / limit.Rate * float64(time.Second))
+	return ratelimit.New(limit, refill)
+}
+
+// Unlock - проверка пароля защищённой ссылки и выдача пропуска.
+// Попытки для каждой ссылки ограничены, сверх лимита возвращается ErrTooManyAttempts.
+// Для ссылки без пароля возвращается пустой пропуск.
+func (s *Shortener) Unlock(ctx context.Context, key, password string) (_ Access, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.Unlock", trace.WithAttributes(attribute.String("shortener.key", key)))
+	defer func() { endSpan(span, err) }()
+
+	rec, err := s.repo.Find(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Access{}, ErrNotFound
+	}
+	if err != nil {
+		return Access{}, err
+	}
+
+	switch {
+	case rec.DeletedFlag:
+		return Access{}, ErrGone
+	case rec.Disabled:
+		return Access{}, ErrDisabled
+	case !rec.NotBefore.IsZero() && s.now().Before(rec.NotBefore):
+		return Access{}, ErrNotActive
+	case !rec.ExpiresAt.IsZero() && !s.now().Before(rec.ExpiresAt):
+		return Access{}, ErrExpired
+	case rec.Exhausted():
+		return Access{}, ErrExhausted
+	case rec.PasswordHash == "":
+		return Access{}, nil
+	}
+
+	// Попытка списывается до сравнения, чтобы перебор не расходовал и процессорное время
+	if s.attempts != nil && !s.attempts.Allow(key).Allowed {
+		return Access{}, ErrTooManyAttempts
+	}
+	if bcrypt.CompareHashAndPassword([]byte(rec.PasswordHash), []byte(password)) != nil {
+		return Access{}, ErrWrongPassword
+	}
+
+	expiresAt := s.now().Add(s.accessTTL).Truncate(time.Second)
+	return Access{Token: s.accessToken(rec, expiresAt), ExpiresAt: expiresAt}, nil
+}
+
+// hashPassword - bcrypt-хеш пароля, пустой для пустого пароля.
+func (s *Shortener) hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > MaxPasswordLength {
+		return "", ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// accessToken - пропуск "<срок unix>.<hmac>". Подпись включает хеш пароля,
+// поэтому смена пароля отзывает выданные пропуска.
+func (s *Shortener) accessToken(rec storage.Record, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	mac := hmac.New(sha256.New, s.accessSecret)
+	mac.Write([]byte(rec.ShortURL + "\n" + expires + "\n" + rec.PasswordHash))
+	return expires + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validAccess - проверка подписи и срока пропуска к ссылке.
+func (s *Shortener) validAccess(rec storage.Record, token string) bool {
+	expires, _, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !s.now().Before(time.Unix(unix, 0)) {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(s.accessToken(rec, time.Unix(unix, 0))))
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/ratelimit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPassword(t *testing.T) {
+	ctx := context.Background()
+	db := storage.New()
+	s := New(db, "http://localhost:8080", WithPasswordAttempts(ratelimit.Limit{Rate: 1.0 / 60, Burst: 2}))
+	s.passwordCost = bcrypt.MinCost
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	shortURL, err := s.ShortenWithDetails(ctx, "user1", "https://docs.example/internal", Details{Password: "secret"})
+	require.NoError(t, err)
+	key := strings.TrimPrefix(shortURL, "http://localhost:8080/")
+
+	rec, err := db.Find(ctx, key)
+	require.NoError(t, err)
+	require.NotEmpty(t, rec.PasswordHash)
+	require.NotContains(t, rec.PasswordHash, "secret")
+
+	t.Run("password required", func(t *testing.T) {
+		_, _, err := s.Redirect(ctx, key)
+		require.ErrorIs(t, err, ErrPasswordRequired)
+
+		_, _, err = s.RedirectWithAccess(ctx, key, "forged.token")
+		require.ErrorIs(t, err, ErrPasswordRequired)
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		_, err := s.Unlock(ctx, key, "wrong")
+		require.ErrorIs(t, err, ErrWrongPassword)
+
+		access, err := s.Unlock(ctx, key, "secret")
+		require.NoError(t, err)
+		require.Equal(t, now.Add(DefaultAccessTTL), access.ExpiresAt)
+
+		originalURL, _, err := s.RedirectWithAccess(ctx, key, access.Token)
+		require.NoError(t, err)
+		require.Equal(t, "https://docs.example/internal", originalURL)
+
+		now = now.Add(DefaultAccessTTL)
+		_, _, err = s.RedirectWithAccess(ctx, key, access.Token)
+		require.ErrorIs(t, err, ErrPasswordRequired)
+	})
+
+	t.Run("too many attempts", func(t *testing.T) {
+		_, err := s.Unlock(ctx, key, "wrong")
+		require.ErrorIs(t, err, ErrTooManyAttempts)
+		_, err = s.Unlock(ctx, key, "secret")
+		require.ErrorIs(t, err, ErrTooManyAttempts)
+	})
+
+	t.Run("attempts unlimited", func(t *testing.T) {
+		WithPasswordAttempts(ratelimit.Limit{})(s)
+		for i := 0; i < 10; i++ {
+			_, err := s.Unlock(ctx, key, "wrong")
+			require.ErrorIs(t, err, ErrWrongPassword)
+		}
+	})
+
+	t.Run("password change revokes access", func(t *testing.T) {
+		s.attempts = newAttemptsLimiter(DefaultPasswordAttempts)
+		access, err := s.Unlock(ctx, key, "secret")
+		require.NoError(t, err)
+
+		password := "changed"
+		_, err = s.UpdateLink(ctx, "user1", key, Patch{Password: &password})
+		require.NoError(t, err)
+		_, _, err = s.RedirectWithAccess(ctx, key, access.Token)
+		require.ErrorIs(t, err, ErrPasswordRequired)
+
+		password = ""
+		rec, err := s.UpdateLink(ctx, "user1", key, Patch{Password: &password})
+		require.NoError(t, err)
+		require.Empty(t, rec.PasswordHash)
+		_, _, err = s.Redirect(ctx, key)
+		require.NoError(t, err)
+
+		access, err = s.Unlock(ctx, key, "anything")
+		require.NoError(t, err)
+		require.Empty(t, access.Token)
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		_, err := s.ShortenWithDetails(ctx, "user1", "https://example.com", Details{Password: strings.Repeat("p", MaxPasswordLength+1)})
+		require.ErrorIs(t, err, ErrInvalidPassword)
+	})
+}
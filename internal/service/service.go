@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/clientip"
+	"github.com/ParkhomenkoDV/URLShortener/internal/ratelimit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/ParkhomenkoDV/URLShortener/internal/tracing"
+	"github.com/ParkhomenkoDV/URLShortener/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Длина генерируемого ключа.
+const keyLength = 8
+
+var (
+	ErrConflict   = errors.New("URL already shortened")
+	ErrNotFound   = errors.New("URL not found")
+	ErrGone       = errors.New("URL deleted")
+	ErrInvalidURL = errors.New("invalid URL format")
+	ErrForbidden  = errors.New("URL belongs to another user")
+	ErrQuota      = errors.New("link quota exceeded")
+	ErrDisabled   = errors.New("URL disabled by moderator")
+	ErrExpired    = errors.New("URL expired")
+	ErrExhausted  = errors.New("URL click limit reached")
+	ErrNotActive  = errors.New("URL not active yet")
+
+	ErrPrecondition    = errors.New("URL version mismatch")
+	ErrInvalidRedirect = errors.New("invalid redirect code")
+	ErrInvalidDetails  = errors.New("invalid title, tags or notes")
+
+	ErrPasswordRequired = errors.New("URL is password protected")
+	ErrWrongPassword    = errors.New("wrong password")
+	ErrTooManyAttempts  = errors.New("too many password attempts")
+	ErrInvalidPassword  = errors.New("invalid password")
+	ErrInvalidMaxClicks = errors.New("invalid max clicks")
+	ErrInvalidWindow    = errors.New("not_after must be later than not_before")
+)
+
+// Quota - ограничение числа создаваемых ссылок для пользователя из контекста.
+type Quota interface {
+	// Reserve - списание n ссылок, false - квота исчерпана.
+	Reserve(ctx context.Context, n int) (bool, error)
+	// Release - возврат n несозданных ссылок.
+	Release(ctx context.Context, n int)
+}
+
+// noQuota - отсутствие ограничений.
+type noQuota struct{}
+
+func (noQuota) Reserve(context.Context, int) (bool, error) { return true, nil }
+func (noQuota) Release(context.Context, int)               {}
+
+// Option - настройка сервиса.
+type Option func(*Shortener)
+
+// WithQuota - ограничение создания ссылок квотой q.
+func WithQuota(q Quota) Option {
+	return func(s *Shortener) {
+		s.quota = q
+	}
+}
+
+// WithAudit - запись создания, изменения и удаления ссылок в журнал аудита log.
+func WithAudit(log *audit.Log) Option {
+	return func(s *Shortener) {
+		s.audit = log
+	}
+}
+
+// WithClock - источник текущего времени для сроков действия ссылок и пропусков.
+func WithClock(now func() time.Time) Option {
+	return func(s *Shortener) {
+		s.now = now
+	}
+}
+
+// Shortener - бизнес-логика сокращения ссылок.
+type Shortener struct {
+	repo    storage.Repository
+	baseURL string
+	quota   Quota
+	audit   *audit.Log
+	now     func() time.Time
+
+	accessSecret []byte             // ключ подписи пропусков к защищённым ссылкам
+	accessTTL    time.Duration      // время жизни пропуска
+	attempts     *ratelimit.Limiter // попытки ввода пароля по ключам ссылок
+	passwordCost int                // стоимость bcrypt
+}
+
+// New - создание сервиса сокращения ссылок.
+func New(repo storage.Repository, baseURL string, opts ...Option) *Shortener {
+	s := &Shortener{
+		repo:    repo,
+		baseURL: baseURL,
+		quota:   noQuota{},
+		now:     time.Now,
+
+		accessTTL:    DefaultAccessTTL,
+		attempts:     newAttemptsLimiter(DefaultPasswordAttempts),
+		passwordCost: bcrypt.DefaultCost,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.accessSecret) == 0 {
+		s.accessSecret = make([]byte, 32)
+		if _, err := rand.Read(s.accessSecret); err != nil {
+			panic("failed to generate secret: " + err.Error())
+		}
+	}
+	return s
+}
+
+// Shorten - сокращение URL от имени пользователя.
+// Если URL уже был сокращён, возвращается существующая ссылка и ErrConflict.
+func (s *Shortener) Shorten(ctx context.Context, userID, rawURL string) (shortURL string, err error) {
+	return s.ShortenWithDetails(ctx, userID, rawURL, Details{})
+}
+
+// ShortenWithDetails - сокращение URL с заголовком, метками и заметками.
+// Уже сокращённый URL возвращается с ErrConflict без изменения его описания.
+func (s *Shortener) ShortenWithDetails(ctx context.Context, userID, rawURL string, d Details) (shortURL string, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.Shorten")
+	defer func() { endSpan(span, err) }()
+
+	if d, err = d.normalize(); err != nil {
+		return "", err
+	}
+	return s.shorten(ctx, userID, rawURL, d, true)
+}
+
+// shorten - сокращение URL. При reserve новая ссылка списывается с квоты,
+// иначе квота уже списана вызывающим.
+func (s *Shortener) shorten(ctx context.Context, userID, rawURL string, d Details, reserve bool) (string, error) {
+	if err := validateURL(rawURL); err != nil {
+		return "", err
+	}
+	originalURL := normalizationURL(rawURL)
+	if d.MaxClicks < 0 {
+		return "", ErrInvalidMaxClicks
+	}
+	if !validWindow(d.NotBefore, d.NotAfter) {
+		return "", ErrInvalidWindow
+	}
+	passwordHash, err := s.hashPassword(d.Password)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if rec, err := s.repo.FindByURL(ctx, originalURL); err == nil {
+			return s.ShortURL(rec.ShortURL), ErrConflict
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			return "", err
+		}
+
+		key, err := s.generateKey(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		if reserve {
+			ok, err := s.quota.Reserve(ctx, 1)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return "", ErrQuota
+			}
+		}
+
+		rec := storage.Record{
+			ShortURL:    key,
+			OriginalURL: originalURL,
+			UserID:      userID,
+			Version:     1,
+			CreatedAt:   s.now().UTC(),
+			Title:       d.Title,
+			Tags:        d.Tags,
+			Notes:       d.Notes,
+
+			PasswordHash: passwordHash,
+			MaxClicks:    d.MaxClicks,
+			NotBefore:    d.NotBefore.UTC(),
+			ExpiresAt:    d.NotAfter.UTC(),
+		}
+		err = s.repo.Create(ctx, rec)
+		if err != nil && reserve {
+			s.quota.Release(ctx, 1)
+		}
+		switch {
+		case err == nil:
+			s.record(ctx, userID, audit.ActionLinkCreate, nil, &rec)
+			return s.ShortURL(key), nil
+		case errors.Is(err, storage.ErrKeyExists), errors.Is(err, storage.ErrURLExists):
+			continue // гонка с параллельным запросом - повторяем
+		default:
+			return "", err
+		}
+	}
+}
+
+// ShortenBatch - сокращение набора URL. Уже сокращённые URL не считаются ошибкой.
+// Результаты возвращаются в порядке исходных URL.
+func (s *Shortener) ShortenBatch(ctx context.Context, userID string, rawURLs []string) (_ []string, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.ShortenBatch", trace.WithAttributes(attribute.Int("shortener.batch_size", len(rawURLs))))
+	defer func() { endSpan(span, err) }()
+
+	for _, rawURL := range rawURLs {
+		if err := validateURL(rawURL); err != nil {
+			return nil, err
+		}
+	}
+
+	// Квота списывается на весь пакет сразу, чтобы не создать его частично
+	ok, err := s.quota.Reserve(ctx, len(rawURLs))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrQuota
+	}
+
+	created := 0
+	defer func() { s.quota.Release(ctx, len(rawURLs)-created) }()
+
+	shortURLs := make([]string, 0, len(rawURLs))
+	for _, rawURL := range rawURLs {
+		shortURL, err := s.shorten(ctx, userID, rawURL, Details{}, false)
+		if err != nil && !errors.Is(err, ErrConflict) {
+			return nil, err
+		}
+		if err == nil {
+			created++
+		}
+		shortURLs = append(shortURLs, shortURL)
+	}
+	return shortURLs, nil
+}
+
+// Resolve - получение исходного URL по ключу.
+func (s *Shortener) Resolve(ctx context.Context, key string) (string, error) {
+	originalURL, _, err := s.Redirect(ctx, key)
+	return originalURL, err
+}
+
+// Redirect - исходный URL и код перенаправления по ключу.
+func (s *Shortener) Redirect(ctx context.Context, key string) (string, int, error) {
+	return s.RedirectWithAccess(ctx, key, "")
+}
+
+// RedirectWithAccess - исходный URL и код перенаправления по ключу с пропуском token, выданным Unlock.
+// Для защищённой паролем ссылки без действующего пропуска возвращается ErrPasswordRequired.
+func (s *Shortener) RedirectWithAccess(ctx context.Context, key, token string) (_ string, code int, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.Resolve", trace.WithAttributes(attribute.String("shortener.key", key)))
+	defer func() { endSpan(span, err) }()
+
+	rec, err := s.repo.Find(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", 0, ErrNotFound
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	if rec.DeletedFlag {
+		return "", 0, ErrGone
+	}
+	if rec.Disabled {
+		return "", 0, ErrDisabled
+	}
+	if !rec.NotBefore.IsZero() && s.now().Before(rec.NotBefore) {
+		return "", 0, ErrNotActive
+	}
+	if !rec.ExpiresAt.IsZero() && !s.now().Before(rec.ExpiresAt) {
+		return "", 0, ErrExpired
+	}
+	if rec.Exhausted() {
+		return "", 0, ErrExhausted
+	}
+	if rec.PasswordHash != "" && !s.validAccess(rec, token) {
+		return "", 0, ErrPasswordRequired
+	}
+
+	originalURL := normalizationURL(rec.OriginalURL)
+	if _, err := url.ParseRequestURI(originalURL); err != nil {
+		return "", 0, ErrInvalidURL
+	}
+	// Лимит переходов окончательно проверяется хранилищем: параллельные переходы могли исчерпать его после Find
+	if _, err := s.repo.Click(ctx, key); errors.Is(err, storage.ErrExhausted) {
+		return "", 0, ErrExhausted
+	} else if err != nil {
+		return "", 0, err
+	}
+
+	code = rec.RedirectCode
+	if code == 0 {
+		code = http.StatusTemporaryRedirect
+	}
+	return originalURL, code, nil
+}
+
+// UserURLs - действующие ссылки пользователя.
+func (s *Shortener) UserURLs(ctx context.Context, userID string) ([]storage.Record, error) {
+	return s.repo.FindByUser(ctx, userID)
+}
+
+// Stats - статистика сервиса.
+func (s *Shortener) Stats(ctx context.Context) (storage.Stats, error) {
+	return s.repo.Stats(ctx)
+}
+
+// Delete - удаление ссылки пользователем-владельцем.
+func (s *Shortener) Delete(ctx context.Context, userID, key string) (err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.Delete", trace.WithAttributes(attribute.String("shortener.key", key)))
+	defer func() { endSpan(span, err) }()
+
+	rec, err := s.repo.Find(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if rec.UserID != userID {
+		return ErrForbidden
+	}
+	if rec.DeletedFlag {
+		return ErrGone
+	}
+
+	if err := s.repo.MarkDeleted(ctx, key); err != nil {
+		return err
+	}
+
+	after := rec
+	after.DeletedFlag = true
+	s.record(ctx, userID, audit.ActionLinkDelete, &rec, &after)
+	return nil
+}
+
+// ShortURL - полная сокращённая ссылка для ключа.
+func (s *Shortener) ShortURL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// generateKey - генерация свободного ключа.
+func (s *Shortener) generateKey(ctx context.Context) (key string, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.generateKey")
+	attempts := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("shortener.keygen_attempts", attempts))
+		endSpan(span, err)
+	}()
+
+	for {
+		attempts++
+		key := utils.GenerateShortURL(keyLength)
+		_, err := s.repo.Find(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			return key, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// record - запись изменения ссылки в журнал аудита от имени actor.
+func (s *Shortener) record(ctx context.Context, actor, action string, before, after *storage.Record) {
+	ev := audit.Event{Actor: actor, IP: clientip.FromContext(ctx), Action: action}
+	if before != nil {
+		ev.Key = before.ShortURL
+		ev.Before = audit.Snapshot(*before)
+	}
+	if after != nil {
+		ev.Key = after.ShortURL
+		ev.After = audit.Snapshot(*after)
+	}
+
+	s.audit.Report(ctx, ev)
+}
+
+// expectedErrors - ответы сервиса, которые не считаются ошибками спана.
+var expectedErrors = []error{
+	ErrConflict, ErrNotFound, ErrGone, ErrQuota, ErrDisabled, ErrExpired, ErrExhausted, ErrNotActive, ErrForbidden,
+	ErrPrecondition, ErrInvalidRedirect, ErrInvalidDetails,
+	ErrPasswordRequired, ErrWrongPassword, ErrTooManyAttempts, ErrInvalidPassword, ErrInvalidMaxClicks,
+	ErrInvalidWindow,
+}
+
+// endSpan - завершение спана операции. Ожидаемые ответы сервиса ошибками спана не считаются.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !slices.ContainsFunc(expectedErrors, func(target error) bool { return errors.Is(err, target) }) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// normalizationURL - нормализация url.
+func normalizationURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		rawURL = "http://" + rawURL
+	}
+
+	return rawURL
+}
+
+// validateURL - валидация url.
+func validateURL(rawURL string) error {
+	rawURL = normalizationURL(rawURL)
+
+	if _, err := url.ParseRequestURI(rawURL); err != nil {
+		return ErrInvalidURL
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortener(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("shorten and resolve", func(t *testing.T) {
+		s := New(storage.New(), "http://localhost:8080")
+
+		shortURL, err := s.Shorten(ctx, "user1", "example.com")
+		require.NoError(t, err)
+		require.Contains(t, shortURL, "http://localhost:8080/")
+
+		key := shortURL[len("http://localhost:8080/"):]
+		require.Len(t, key, keyLength)
+
+		originalURL, err := s.Resolve(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "http://example.com", originalURL)
+	})
+
+	t.Run("conflict on repeated URL", func(t *testing.T) {
+		s := New(storage.New(), "http://localhost:8080")
+
+		first, err := s.Shorten(ctx, "user1", "https://example.com")
+		require.NoError(t, err)
+
+		second, err := s.Shorten(ctx, "user1", "https://example.com")
+		require.ErrorIs(t, err, ErrConflict)
+		require.Equal(t, first, second)
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		s := New(storage.New(), "http://localhost:8080")
+
+		_, err := s.Shorten(ctx, "user1", "y a n d e x")
+		require.ErrorIs(t, err, ErrInvalidURL)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s := New(storage.New(), "http://localhost:8080")
+
+		_, err := s.Resolve(ctx, "unknown")
+		require.ErrorIs(t, err, ErrNotFound)
+		require.ErrorIs(t, s.Delete(ctx, "user1", "unknown"), ErrNotFound)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		db := storage.New()
+		s := New(db, "http://localhost:8080")
+		db.Create(ctx, storage.Record{ShortURL: "key", OriginalURL: "https://example.com", UserID: "user1"})
+
+		require.ErrorIs(t, s.Delete(ctx, "user2", "key"), ErrForbidden)
+
+		require.NoError(t, s.Delete(ctx, "user1", "key"))
+		_, err := s.Resolve(ctx, "key")
+		require.ErrorIs(t, err, ErrGone)
+		require.ErrorIs(t, s.Delete(ctx, "user1", "key"), ErrGone)
+
+		// после удаления URL можно сократить заново
+		_, err = s.Shorten(ctx, "user1", "https://example.com")
+		require.NoError(t, err)
+	})
+
+	t.Run("batch and user URLs", func(t *testing.T) {
+		s := New(storage.New(), "http://localhost:8080")
+
+		existing, err := s.Shorten(ctx, "user1", "https://example.com")
+		require.NoError(t, err)
+
+		shortURLs, err := s.ShortenBatch(ctx, "user1", []string{"https://google.com", "https://example.com"})
+		require.NoError(t, err)
+		require.Len(t, shortURLs, 2)
+		require.Equal(t, existing, shortURLs[1])
+
+		_, err = s.ShortenBatch(ctx, "user1", []string{"https://github.com", "y a n d e x"})
+		require.ErrorIs(t, err, ErrInvalidURL)
+
+		records, err := s.UserURLs(ctx, "user1")
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		stats, err := s.Stats(ctx)
+		require.NoError(t, err)
+		require.Equal(t, storage.Stats{URLs: 2, Users: 1}, stats)
+	})
+}
+
+// limitQuota - квота на limit ссылок.
+type limitQuota struct {
+	limit int
+}
+
+func (q *limitQuota) Reserve(_ context.Context, n int) (bool, error) {
+	if n > q.limit {
+		return false, nil
+	}
+	q.limit -= n
+	return true, nil
+}
+
+func (q *limitQuota) Release(_ context.Context, n int) {
+	q.limit += n
+}
+
+func TestShortenerQuota(t *testing.T) {
+	ctx := context.Background()
+	q := &limitQuota{limit: 3}
+	s := New(storage.New(), "http://localhost:8080", WithQuota(q))
+
+	existing, err := s.Shorten(ctx, "user1", "https://example.com")
+	require.NoError(t, err)
+
+	t.Run("conflict does not consume quota", func(t *testing.T) {
+		again, err := s.Shorten(ctx, "user1", "https://example.com")
+		require.ErrorIs(t, err, ErrConflict)
+		require.Equal(t, existing, again)
+		require.Equal(t, 2, q.limit)
+	})
+
+	t.Run("batch over quota is rejected entirely", func(t *testing.T) {
+		_, err := s.ShortenBatch(ctx, "user1", []string{"https://a.example", "https://b.example", "https://c.example"})
+		require.ErrorIs(t, err, ErrQuota)
+		require.Equal(t, 2, q.limit)
+
+		records, err := s.UserURLs(ctx, "user1")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("batch releases unused reservation", func(t *testing.T) {
+		_, err := s.ShortenBatch(ctx, "user1", []string{"https://a.example", "https://example.com"})
+		require.NoError(t, err)
+		require.Equal(t, 1, q.limit)
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		_, err := s.Shorten(ctx, "user1", "https://b.example")
+		require.NoError(t, err)
+
+		_, err = s.Shorten(ctx, "user1", "https://c.example")
+		require.ErrorIs(t, err, ErrQuota)
+	})
+}
+
+func TestShortenerAudit(t *testing.T) {
+	ctx := context.Background()
+	log, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	defer log.Close()
+
+	s := New(storage.New(), "http://localhost:8080", WithAudit(log))
+
+	shortURL, err := s.Shorten(ctx, "user1", "https://example.com")
+	require.NoError(t, err)
+	key := shortURL[len("http://localhost:8080/"):]
+
+	// Повтор уже сокращённого URL ничего не создаёт
+	_, err = s.Shorten(ctx, "user1", "https://example.com")
+	require.ErrorIs(t, err, ErrConflict)
+
+	require.ErrorIs(t, s.Delete(ctx, "user2", key), ErrForbidden)
+	require.NoError(t, s.Delete(ctx, "user1", key))
+
+	var events []audit.Event
+	require.NoError(t, log.Query(audit.Filter{}, func(ev audit.Event) error {
+		events = append(events, ev)
+		return nil
+	}))
+	require.Len(t, events, 2)
+
+	require.Equal(t, "user1", events[0].Actor)
+	require.Equal(t, audit.ActionLinkCreate, events[0].Action)
+	require.Equal(t, key, events[0].Key)
+	require.Nil(t, events[0].Before)
+	require.Equal(t, "https://example.com", events[0].After.OriginalURL)
+
+	require.Equal(t, audit.ActionLinkDelete, events[1].Action)
+	require.False(t, events[1].Before.Deleted)
+	require.True(t, events[1].After.Deleted)
+}
+
+func Test_normalizationURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		want   string
+	}{
+		{"1", "yandex.ru", "http://yandex.ru"},
+		{"2", "http://yandex.ru", "http://yandex.ru"},
+		{"3", "https://yandex.ru", "https://yandex.ru"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizationURL(tt.rawURL)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_validateURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawURL  string
+		wantErr bool
+	}{
+		{"1", "yandex.ru", false},
+		{"2", "http://yandex.ru", false},
+		{"3", "https://yandex.ru", false},
+		{"4", "y a n d e x", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateURL(tt.rawURL); (err != nil) != tt.wantErr {
+				t.Errorf("validateURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
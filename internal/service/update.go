@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/audit"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/ParkhomenkoDV/URLShortener/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxHistory - число хранимых предыдущих версий ссылки.
+const MaxHistory = 20
+
+// redirectCodes - допустимые коды перенаправления.
+var redirectCodes = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusSeeOther,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+// Patch - изменение ссылки владельцем. Поля nil не меняются.
+type Patch struct {
+	OriginalURL  *string
+	RedirectCode *int
+	ExpiresAt    *time.Time         // окончание действия, нулевое время снимает срок
+	NotBefore    *time.Time         // начало действия, нулевое время делает ссылку действующей сразу
+	Metadata     map[string]*string // nil-значение удаляет ключ
+	Title        *string            // заголовок
+	Tags         *[]string          // метки, заменяют все прежние
+	Notes        *string            // заметки
+	Password     *string            // пароль доступа, пустая строка снимает защиту
+	IfMatch      []int              // версии, одной из которых должна быть текущая; пусто - без проверки
+}
+
+// Link - ссылка пользователя-владельца вместе с историей версий.
+func (s *Shortener) Link(ctx context.Context, userID, key string) (storage.Record, error) {
+	rec, err := s.repo.Find(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.Record{}, ErrNotFound
+	}
+	if err != nil {
+		return storage.Record{}, err
+	}
+
+	if rec.UserID != userID {
+		return storage.Record{}, ErrForbidden
+	}
+	if rec.DeletedFlag {
+		return storage.Record{}, ErrGone
+	}
+	return rec, nil
+}
+
+// Status - состояние ссылки на текущий момент по часам сервиса.
+func (s *Shortener) Status(rec storage.Record) string {
+	return storage.Status(rec, s.now())
+}
+
+// UpdateLink - изменение цели, кода перенаправления, срока действия, метаданных, описания и пароля ссылки владельцем.
+// Каждое изменение увеличивает версию, предыдущая цель сохраняется в истории.
+func (s *Shortener) UpdateLink(ctx context.Context, userID, key string, p Patch) (_ storage.Record, err error) {
+	ctx, span := tracing.Tracer("internal/service").Start(ctx, "service.UpdateLink", trace.WithAttributes(attribute.String("shortener.key", key)))
+	defer func() { endSpan(span, err) }()
+
+	if p.OriginalURL != nil {
+		if err := validateURL(*p.OriginalURL); err != nil {
+			return storage.Record{}, err
+		}
+		originalURL := normalizationURL(*p.OriginalURL)
+		p.OriginalURL = &originalURL
+	}
+	if p.RedirectCode != nil && !slices.Contains(redirectCodes, *p.RedirectCode) {
+		return storage.Record{}, ErrInvalidRedirect
+	}
+	var d Details
+	if p.Title != nil {
+		d.Title = *p.Title
+	}
+	if p.Tags != nil {
+		d.Tags = *p.Tags
+	}
+	if p.Notes != nil {
+		d.Notes = *p.Notes
+	}
+	if d, err = d.normalize(); err != nil {
+		return storage.Record{}, err
+	}
+	var passwordHash string
+	if p.Password != nil {
+		if passwordHash, err = s.hashPassword(*p.Password); err != nil {
+			return storage.Record{}, err
+		}
+	}
+
+	var before storage.Record
+	after, err := s.repo.Update(ctx, key, func(rec *storage.Record) error {
+		if rec.UserID != userID {
+			return ErrForbidden
+		}
+		if rec.DeletedFlag {
+			return ErrGone
+		}
+		if len(p.IfMatch) > 0 && !slices.Contains(p.IfMatch, rec.Version) {
+			return ErrPrecondition
+		}
+
+		before = *rec
+		now := s.now().UTC()
+
+		// Срезы и карты записи могут разделяться с хранилищем, поэтому не изменяются на месте
+		rec.History = append(slices.Clip(rec.History), storage.Revision{
+			Version:      rec.Version,
+			OriginalURL:  rec.OriginalURL,
+			RedirectCode: rec.RedirectCode,
+			ExpiresAt:    rec.ExpiresAt,
+			ReplacedAt:   now,
+		})
+		if len(rec.History) > MaxHistory {
+			rec.History = rec.History[len(rec.History)-MaxHistory:]
+		}
+
+		if p.OriginalURL != nil {
+			rec.OriginalURL = *p.OriginalURL
+		}
+		if p.RedirectCode != nil {
+			rec.RedirectCode = *p.RedirectCode
+		}
+		if p.ExpiresAt != nil {
+			rec.ExpiresAt = p.ExpiresAt.UTC()
+		}
+		if p.NotBefore != nil {
+			rec.NotBefore = p.NotBefore.UTC()
+		}
+		if !validWindow(rec.NotBefore, rec.ExpiresAt) {
+			return ErrInvalidWindow
+		}
+		if p.Metadata != nil {
+			metadata := maps.Clone(rec.Metadata)
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			for name, value := range p.Metadata {
+				if value == nil {
+					delete(metadata, name)
+				} else {
+					metadata[name] = *value
+				}
+			}
+			if len(metadata) == 0 {
+				metadata = nil
+			}
+			rec.Metadata = metadata
+		}
+		if p.Title != nil {
+			rec.Title = d.Title
+		}
+		if p.Tags != nil {
+			rec.Tags = d.Tags
+		}
+		if p.Notes != nil {
+			rec.Notes = d.Notes
+		}
+		if p.Password != nil {
+			rec.PasswordHash = passwordHash
+		}
+
+		rec.Version++
+		rec.UpdatedAt = now
+		return nil
+	})
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return storage.Record{}, ErrNotFound
+	case errors.Is(err, storage.ErrURLExists):
+		return storage.Record{}, ErrConflict
+	case err != nil:
+		return storage.Record{}, err
+	}
+
+	s.record(ctx, userID, audit.ActionLinkUpdate, &before, &after)
+	return after, nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateLink(t *testing.T) {
+	ctx := context.Background()
+	db := storage.New()
+	s := New(db, "http://localhost:8080")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	shortURL, err := s.Shorten(ctx, "user1", "https://example.com")
+	require.NoError(t, err)
+	key := shortURL[len("http://localhost:8080/"):]
+	_, err = s.Shorten(ctx, "user1", "https://google.com")
+	require.NoError(t, err)
+
+	ptr := func(v string) *string { return &v }
+	code := http.StatusMovedPermanently
+
+	t.Run("change destination and code", func(t *testing.T) {
+		rec, err := s.UpdateLink(ctx, "user1", key, Patch{
+			OriginalURL:  ptr("https://example.org/new"),
+			RedirectCode: &code,
+			Metadata:     map[string]*string{"campaign": ptr("spring")},
+			IfMatch:      []int{1},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "https://example.org/new", rec.OriginalURL)
+		require.Equal(t, 2, rec.Version)
+		require.Equal(t, now, rec.UpdatedAt)
+		require.Equal(t, map[string]string{"campaign": "spring"}, rec.Metadata)
+		require.Equal(t, []storage.Revision{{Version: 1, OriginalURL: "https://example.com", ReplacedAt: now}}, rec.History)
+
+		originalURL, redirect, err := s.Redirect(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, "https://example.org/new", originalURL)
+		require.Equal(t, http.StatusMovedPermanently, redirect)
+
+		// Старая цель освобождается, новая занята
+		_, err = db.FindByURL(ctx, "https://example.com")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		_, err := s.UpdateLink(ctx, "user1", key, Patch{OriginalURL: ptr("https://example.net"), IfMatch: []int{1}})
+		require.ErrorIs(t, err, ErrPrecondition)
+
+		rec, err := s.Link(ctx, "user1", key)
+		require.NoError(t, err)
+		require.Equal(t, 2, rec.Version)
+	})
+
+	t.Run("expiry and metadata removal", func(t *testing.T) {
+		expiresAt := now.Add(time.Hour)
+		rec, err := s.UpdateLink(ctx, "user1", key, Patch{ExpiresAt: &expiresAt, Metadata: map[string]*string{"campaign": nil}})
+		require.NoError(t, err)
+		require.Nil(t, rec.Metadata)
+		require.Len(t, rec.History, 2)
+
+		now = now.Add(2 * time.Hour)
+		_, _, err = s.Redirect(ctx, key)
+		require.ErrorIs(t, err, ErrExpired)
+
+		_, err = s.UpdateLink(ctx, "user1", key, Patch{ExpiresAt: &time.Time{}})
+		require.NoError(t, err)
+		_, _, err = s.Redirect(ctx, key)
+		require.NoError(t, err)
+	})
+
+	t.Run("history is capped", func(t *testing.T) {
+		for range MaxHistory + 5 {
+			_, err := s.UpdateLink(ctx, "user1", key, Patch{Metadata: map[string]*string{}})
+			require.NoError(t, err)
+		}
+		rec, err := s.Link(ctx, "user1", key)
+		require.NoError(t, err)
+		require.Len(t, rec.History, MaxHistory)
+		require.Equal(t, rec.Version-1, rec.History[MaxHistory-1].Version)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := s.UpdateLink(ctx, "user2", key, Patch{})
+		require.ErrorIs(t, err, ErrForbidden)
+
+		_, err = s.UpdateLink(ctx, "user1", "unknown", Patch{})
+		require.ErrorIs(t, err, ErrNotFound)
+
+		_, err = s.UpdateLink(ctx, "user1", key, Patch{OriginalURL: ptr("not a url")})
+		require.ErrorIs(t, err, ErrInvalidURL)
+
+		invalid := http.StatusOK
+		_, err = s.UpdateLink(ctx, "user1", key, Patch{RedirectCode: &invalid})
+		require.ErrorIs(t, err, ErrInvalidRedirect)
+
+		_, err = s.UpdateLink(ctx, "user1", key, Patch{OriginalURL: ptr("https://google.com")})
+		require.ErrorIs(t, err, ErrConflict)
+
+		require.NoError(t, s.Delete(ctx, "user1", key))
+		_, err = s.UpdateLink(ctx, "user1", key, Patch{})
+		require.ErrorIs(t, err, ErrGone)
+	})
+}
@@ -0,0 +1,101 @@
+// Package bloom - фильтр Блума существующих ключей, отсекающий обращения к хранилищу
+// для заведомо отсутствующих ключей.
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// Filter - фильтр Блума для строк. Безопасен для конкурентного использования.
+type Filter struct {
+	mu     sync.RWMutex
+	bits   []uint64
+	m      uint64 // число бит
+	k      uint64 // число хеш-функций
+	n      uint64 // число добавленных элементов
+	fpRate float64
+}
+
+// NewFilter - фильтр на capacity элементов с вероятностью ложного срабатывания fpRate.
+func NewFilter(capacity int, fpRate float64) *Filter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	// m = -n*ln(p)/ln(2)^2, k = m/n*ln(2)
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(capacity)*math.Ln2)))
+
+	return &Filter{
+		bits:   make([]uint64, m/64),
+		m:      m,
+		k:      k,
+		fpRate: fpRate,
+	}
+}
+
+// Add - добавление ключа.
+func (f *Filter) Add(key string) {
+	h1, h2 := hashes(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.n++
+}
+
+// MayContain - false, если ключ заведомо не добавлялся.
+func (f *Filter) MayContain(key string) bool {
+	h1, h2 := hashes(key)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len - число добавленных ключей.
+func (f *Filter) Len() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return int(f.n)
+}
+
+// Capacity - число ключей, на которое рассчитан фильтр.
+func (f *Filter) Capacity() int {
+	return int(math.Round(float64(f.m) / float64(f.k) * math.Ln2))
+}
+
+// EstimatedFPRate - ожидаемая вероятность ложного срабатывания при текущем заполнении.
+func (f *Filter) EstimatedFPRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return math.Pow(1-math.Exp(-float64(f.k*f.n)/float64(f.m)), float64(f.k))
+}
+
+// hashes - две независимые хеш-функции для двойного хеширования.
+func hashes(key string) (uint64, uint64) {
+	a := fnv.New64a()
+	a.Write([]byte(key))
+	b := fnv.New64()
+	b.Write([]byte(key))
+	return a.Sum64(), b.Sum64() | 1
+}
@@ -0,0 +1,43 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	t.Run("no false negatives", func(t *testing.T) {
+		f := NewFilter(1000, 0.01)
+		for i := 0; i < 1000; i++ {
+			f.Add(fmt.Sprintf("key-%d", i))
+		}
+		for i := 0; i < 1000; i++ {
+			require.True(t, f.MayContain(fmt.Sprintf("key-%d", i)))
+		}
+		require.Equal(t, 1000, f.Len())
+	})
+
+	t.Run("false positive rate", func(t *testing.T) {
+		f := NewFilter(10000, 0.01)
+		for i := 0; i < 10000; i++ {
+			f.Add(fmt.Sprintf("key-%d", i))
+		}
+
+		positives := 0
+		for i := 0; i < 10000; i++ {
+			if f.MayContain(fmt.Sprintf("other-%d", i)) {
+				positives++
+			}
+		}
+		require.Less(t, positives, 300)
+		require.InDelta(t, 0.01, f.EstimatedFPRate(), 0.005)
+	})
+
+	t.Run("capacity", func(t *testing.T) {
+		f := NewFilter(5000, 0.001)
+		require.InDelta(t, 5000, f.Capacity(), 100)
+		require.Zero(t, f.EstimatedFPRate())
+	})
+}
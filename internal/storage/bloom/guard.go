@@ -0,0 +1,140 @@
+package bloom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// Минимальная ёмкость фильтра, чтобы не перестраивать его на маленькой базе.
+const minCapacity = 1 << 16
+
+// Metrics - счётчики фильтра.
+type Metrics struct {
+	Keys            int     // ключей в фильтре
+	Capacity        int     // ёмкость фильтра
+	Rejected        uint64  // поисков, отсечённых фильтром
+	FalsePositives  uint64  // ключей, пропущенных фильтром, но отсутствующих в хранилище
+	EstimatedFPRate float64 // ожидаемая доля ложных срабатываний
+	TargetFPRate    float64 // заданная доля ложных срабатываний
+}
+
+// Guard - декоратор хранилища, отвечающий ErrNotFound без обращения к хранилищу
+// для ключей, которых заведомо нет. Остальные методы storage.Store передаются как есть.
+type Guard struct {
+	storage.Store
+
+	fpRate float64
+
+	mu     sync.RWMutex // защищает замену filter при перестроении
+	filter *Filter
+
+	rebuilding     atomic.Bool
+	rejected       atomic.Uint64
+	falsePositives atomic.Uint64
+}
+
+// New - фильтр по всем ключам store с вероятностью ложного срабатывания fpRate.
+func New(ctx context.Context, store storage.Store, fpRate float64) (*Guard, error) {
+	g := &Guard{Store: store, fpRate: fpRate}
+	if err := g.Rebuild(ctx); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Rebuild - перестроение фильтра по ключам хранилища с запасом ёмкости.
+func (g *Guard) Rebuild(ctx context.Context) error {
+	var keys []string
+	err := g.Store.ForEachKey(ctx, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	filter := NewFilter(max(2*len(keys), minCapacity), g.fpRate)
+	for _, key := range keys {
+		filter.Add(key)
+	}
+
+	g.mu.Lock()
+	old := g.filter
+	g.filter = filter
+	g.mu.Unlock()
+
+	// Ключи, записанные во время обхода, досматриваем повторным проходом
+	if old != nil {
+		return g.Store.ForEachKey(ctx, func(key string) error {
+			if !filter.MayContain(key) {
+				filter.Add(key)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// Find - поиск по ключу, если фильтр допускает его наличие.
+func (g *Guard) Find(ctx context.Context, key string) (storage.Record, error) {
+	if !g.current().MayContain(key) {
+		g.rejected.Add(1)
+		return storage.Record{}, storage.ErrNotFound
+	}
+
+	rec, err := g.Store.Find(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		g.falsePositives.Add(1)
+	}
+	return rec, err
+}
+
+// Create - добавление записи и её ключа в фильтр.
+func (g *Guard) Create(ctx context.Context, rec storage.Record) error {
+	// Ключ добавляется до записи, чтобы параллельный поиск не получил ложный отказ
+	filter := g.current()
+	filter.Add(rec.ShortURL)
+
+	if err := g.Store.Create(ctx, rec); err != nil {
+		return err
+	}
+	// Фильтр мог смениться во время записи - тогда ключ нужен и в новом
+	if current := g.current(); current != filter {
+		current.Add(rec.ShortURL)
+		filter = current
+	}
+
+	// Заполненный фильтр теряет точность - перестраиваем в фоне с удвоенной ёмкостью
+	if filter.Len() > filter.Capacity() && g.rebuilding.CompareAndSwap(false, true) {
+		go func() {
+			defer g.rebuilding.Store(false)
+			g.Rebuild(context.Background())
+		}()
+	}
+	return nil
+}
+
+// Metrics - текущие значения счётчиков.
+func (g *Guard) Metrics() Metrics {
+	filter := g.current()
+	return Metrics{
+		Keys:            filter.Len(),
+		Capacity:        filter.Capacity(),
+		Rejected:        g.rejected.Load(),
+		FalsePositives:  g.falsePositives.Load(),
+		EstimatedFPRate: filter.EstimatedFPRate(),
+		TargetFPRate:    g.fpRate,
+	}
+}
+
+// current - действующий фильтр.
+func (g *Guard) current() *Filter {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.filter
+}
@@ -0,0 +1,81 @@
+package bloom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore - хранилище, считающее обращения к Find.
+type countingStore struct {
+	*storage.DB
+	finds int
+}
+
+func (s *countingStore) Find(ctx context.Context, key string) (storage.Record, error) {
+	s.finds++
+	return s.DB.Find(ctx, key)
+}
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rebuilt on load", func(t *testing.T) {
+		store := &countingStore{DB: storage.New()}
+		store.Set("key", "https://example.com")
+
+		g, err := New(ctx, store, 0.01)
+		require.NoError(t, err)
+
+		rec, err := g.Find(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com", rec.OriginalURL)
+		require.Equal(t, 1, store.finds)
+	})
+
+	t.Run("absent keys skip storage", func(t *testing.T) {
+		store := &countingStore{DB: storage.New()}
+		g, err := New(ctx, store, 0.01)
+		require.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			_, err := g.Find(ctx, fmt.Sprintf("missing-%d", i))
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		}
+		m := g.Metrics()
+		require.Equal(t, uint64(100)-m.FalsePositives, m.Rejected)
+		require.Equal(t, int(m.FalsePositives), store.finds)
+		require.Equal(t, 0.01, m.TargetFPRate)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		store := &countingStore{DB: storage.New()}
+		g, err := New(ctx, store, 0.01)
+		require.NoError(t, err)
+
+		require.NoError(t, g.Create(ctx, storage.Record{ShortURL: "key", OriginalURL: "https://example.com"}))
+		_, err = g.Find(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, 1, g.Metrics().Keys)
+	})
+
+	t.Run("rebuild keeps keys", func(t *testing.T) {
+		store := &countingStore{DB: storage.New()}
+		g, err := New(ctx, store, 0.01)
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, g.Create(ctx, storage.Record{ShortURL: fmt.Sprintf("key-%d", i), OriginalURL: fmt.Sprintf("https://example.com/%d", i)}))
+		}
+		require.NoError(t, g.Rebuild(ctx))
+
+		for i := 0; i < 10; i++ {
+			_, err := g.Find(ctx, fmt.Sprintf("key-%d", i))
+			require.NoError(t, err)
+		}
+		require.Equal(t, 10, g.Metrics().Keys)
+	})
+}
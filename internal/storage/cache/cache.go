@@ -0,0 +1,202 @@
+// Package cache - LRU-кэш с TTL поверх медленных хранилищ.
+// Кэшируется поиск по ключу, в том числе отсутствие ключа (негативное кэширование).
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+)
+
+// Metrics - счётчики кэша.
+type Metrics struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+// entry - закэшированный результат поиска по ключу.
+type entry struct {
+	key     string
+	rec     storage.Record
+	found   bool
+	expires time.Time
+}
+
+// Cache - декоратор хранилища. Остальные методы storage.Store передаются как есть.
+type Cache struct {
+	storage.Store
+
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	lru   *list.List // от недавно использованных к давно использованным
+	items map[string]*list.Element
+	gen   uint64 // номер поколения, растёт при каждой инвалидации
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// New - кэш на size записей со временем жизни ttl поверх store.
+func New(store storage.Store, size int, ttl time.Duration) *Cache {
+	return &Cache{
+		Store: store,
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		lru:   list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Find - поиск записи по ключу через кэш.
+func (c *Cache) Find(ctx context.Context, key string) (storage.Record, error) {
+	if rec, found, ok := c.get(key); ok {
+		c.hits.Add(1)
+		if !found {
+			return storage.Record{}, storage.ErrNotFound
+		}
+		return rec, nil
+	}
+	c.misses.Add(1)
+
+	gen := c.generation()
+	rec, err := c.Store.Find(ctx, key)
+	switch {
+	case err == nil:
+		c.set(key, rec, true, gen)
+	case errors.Is(err, storage.ErrNotFound):
+		c.set(key, storage.Record{}, false, gen)
+	}
+	return rec, err
+}
+
+// Create - добавление записи со сбросом негативной записи кэша для ключа.
+func (c *Cache) Create(ctx context.Context, rec storage.Record) error {
+	err := c.Store.Create(ctx, rec)
+	c.invalidate(rec.ShortURL)
+	return err
+}
+
+// MarkDeleted - удаление записи со сбросом кэша для ключа.
+func (c *Cache) MarkDeleted(ctx context.Context, key string) error {
+	err := c.Store.MarkDeleted(ctx, key)
+	c.invalidate(key)
+	return err
+}
+
+// Update - изменение записи со сбросом кэша для ключа.
+func (c *Cache) Update(ctx context.Context, key string, fn func(rec *storage.Record) error) (storage.Record, error) {
+	rec, err := c.Store.Update(ctx, key, fn)
+	c.invalidate(key)
+	return rec, err
+}
+
+// Click - учёт перехода с обновлением записи в кэше. Переход по ссылке не должен
+// вытеснять её из кэша; при параллельных переходах счётчик в кэше может ненадолго отставать.
+func (c *Cache) Click(ctx context.Context, key string) (storage.Record, error) {
+	gen := c.generation()
+	rec, err := c.Store.Click(ctx, key)
+	if err == nil {
+		c.set(key, rec, true, gen)
+	}
+	return rec, err
+}
+
+// Metrics - текущие значения счётчиков.
+func (c *Cache) Metrics() Metrics {
+	c.mu.Lock()
+	size := c.lru.Len()
+	c.mu.Unlock()
+
+	return Metrics{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+	}
+}
+
+// get - действующая запись кэша.
+func (c *Cache) get(key string) (storage.Record, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return storage.Record{}, false, false
+	}
+
+	e := elem.Value.(*entry)
+	if c.now().After(e.expires) {
+		c.remove(elem)
+		return storage.Record{}, false, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return e.rec, e.found, true
+}
+
+// generation - текущее поколение кэша.
+func (c *Cache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// set - запись в кэш с вытеснением давно не использованных записей.
+// Результат, прочитанный до инвалидации (поколение gen устарело), не кэшируется.
+func (c *Cache) set(key string, rec storage.Record, found bool, gen uint64) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	e := &entry{key: key, rec: rec, found: found, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.items[key]; ok {
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// invalidate - удаление ключа из кэша.
+func (c *Cache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// remove - удаление элемента без блокировки.
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(*entry).key)
+}
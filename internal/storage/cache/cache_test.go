@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore - хранилище, считающее обращения к Find.
+type countingStore struct {
+	*storage.DB
+	finds int
+}
+
+func (s *countingStore) Find(ctx context.Context, key string) (storage.Record, error) {
+	s.finds++
+	return s.DB.Find(ctx, key)
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	newCache := func(size int) (*Cache, *countingStore, *time.Time) {
+		store := &countingStore{DB: storage.New()}
+		c := New(store, size, time.Minute)
+		clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		c.now = func() time.Time { return clock }
+		return c, store, &clock
+	}
+
+	t.Run("read through", func(t *testing.T) {
+		c, store, _ := newCache(10)
+		store.Set("key", "https://example.com")
+
+		for i := 0; i < 3; i++ {
+			rec, err := c.Find(ctx, "key")
+			require.NoError(t, err)
+			require.Equal(t, "https://example.com", rec.OriginalURL)
+		}
+		require.Equal(t, 1, store.finds)
+		require.Equal(t, Metrics{Hits: 2, Misses: 1, Size: 1}, c.Metrics())
+	})
+
+	t.Run("negative caching and create", func(t *testing.T) {
+		c, store, _ := newCache(10)
+
+		_, err := c.Find(ctx, "key")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		_, err = c.Find(ctx, "key")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		require.Equal(t, 1, store.finds)
+
+		require.NoError(t, c.Create(ctx, storage.Record{ShortURL: "key", OriginalURL: "https://example.com"}))
+		_, err = c.Find(ctx, "key")
+		require.NoError(t, err)
+	})
+
+	t.Run("invalidation on delete", func(t *testing.T) {
+		c, store, _ := newCache(10)
+		store.Set("key", "https://example.com")
+
+		_, err := c.Find(ctx, "key")
+		require.NoError(t, err)
+		require.NoError(t, c.MarkDeleted(ctx, "key"))
+
+		rec, err := c.Find(ctx, "key")
+		require.NoError(t, err)
+		require.True(t, rec.DeletedFlag)
+	})
+
+	t.Run("invalidation on update", func(t *testing.T) {
+		c, store, _ := newCache(10)
+		store.Set("key", "https://example.com")
+
+		_, err := c.Find(ctx, "key")
+		require.NoError(t, err)
+		_, err = c.Update(ctx, "key", func(rec *storage.Record) error {
+			rec.Disabled = true
+			return nil
+		})
+		require.NoError(t, err)
+
+		rec, err := c.Find(ctx, "key")
+		require.NoError(t, err)
+		require.True(t, rec.Disabled)
+	})
+
+	t.Run("refresh on click", func(t *testing.T) {
+		c, store, _ := newCache(10)
+		store.Set("key", "https://example.com")
+
+		_, err := c.Find(ctx, "key")
+		require.NoError(t, err)
+		_, err = c.Click(ctx, "key")
+		require.NoError(t, err)
+
+		rec, err := c.Find(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), rec.Clicks)
+		require.Equal(t, 1, store.finds)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		c, store, clock := newCache(10)
+		store.Set("key", "https://example.com")
+
+		c.Find(ctx, "key")
+		*clock = clock.Add(2 * time.Minute)
+		c.Find(ctx, "key")
+		require.Equal(t, 2, store.finds)
+	})
+
+	t.Run("lru eviction", func(t *testing.T) {
+		c, store, _ := newCache(2)
+		store.Set("a", "https://a.example")
+		store.Set("b", "https://b.example")
+		store.Set("c", "https://c.example")
+
+		c.Find(ctx, "a")
+		c.Find(ctx, "b")
+		c.Find(ctx, "a") // b становится самым давним
+		c.Find(ctx, "c")
+		require.Equal(t, uint64(1), c.Metrics().Evictions)
+
+		finds := store.finds
+		c.Find(ctx, "a")
+		require.Equal(t, finds, store.finds)
+		c.Find(ctx, "b")
+		require.Equal(t, finds+1, store.finds)
+	})
+}
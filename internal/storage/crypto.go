@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Алгоритм шифрования файла хранилища.
+const (
+	encryptionAlgorithm = "aes-256-gcm"
+	keySize             = 32
+	nonceSize           = 12  // стандартный nonce AES-GCM
+	maxKeyIDLength      = 255 // длина идентификатора ключа в SealValue занимает один байт
+)
+
+var ErrUnknownKey = errors.New("unknown storage encryption key")
+
+// Keyring - ключи шифрования файла хранилища.
+// Файл шифруется основным ключом, расшифровывается любым ключом по идентификатору из заголовка,
+// поэтому при ротации старые ключи остаются в наборе до перешифрования файла.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring - пустой набор ключей.
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string][]byte)}
+}
+
+// Add - добавление ключа. Первый добавленный ключ становится основным.
+func (k *Keyring) Add(id string, key []byte) error {
+	if id == "" || len(id) > maxKeyIDLength || strings.ContainsAny(id, ":\n") {
+		return fmt.Errorf("invalid key id %q", id)
+	}
+	if len(key) != keySize {
+		return fmt.Errorf("key %q: expected %d bytes, got %d", id, keySize, len(key))
+	}
+	if _, exists := k.keys[id]; exists {
+		return fmt.Errorf("duplicate key id %q", id)
+	}
+
+	k.keys[id] = key
+	if k.primary == "" {
+		k.primary = id
+	}
+	return nil
+}
+
+// Primary - идентификатор основного ключа.
+func (k *Keyring) Primary() string {
+	if k == nil {
+		return ""
+	}
+	return k.primary
+}
+
+// Merge - добавление ключей другого набора, отсутствующих в этом. Основной ключ не меняется.
+func (k *Keyring) Merge(other *Keyring) {
+	if other == nil {
+		return
+	}
+	for id, key := range other.keys {
+		if _, exists := k.keys[id]; !exists {
+			k.keys[id] = key
+		}
+	}
+	if k.primary == "" {
+		k.primary = other.primary
+	}
+}
+
+// GenerateKey - новый случайный ключ в формате "<id>:<base64>".
+func GenerateKey(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, ":\n") {
+		return "", fmt.Errorf("invalid key id %q", id)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKeyring - ключи в формате "<id>:<base64>", по одному на строку; пустые строки и # игнорируются.
+func ParseKeyring(text string) (*Keyring, error) {
+	k := NewKeyring()
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, encoded, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected <id>:<base64 key>", n+1)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		if err := k.Add(strings.TrimSpace(id), key); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+	return k, nil
+}
+
+// LoadKeyring - набор из ключа key и файла ключей keyFile. Основной - key, если задан,
+// иначе первый ключ файла. Без ключей возвращает nil - файл хранится открытым.
+func LoadKeyring(key, keyFile string) (*Keyring, error) {
+	text := key
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		text += "\n" + string(data)
+	}
+
+	k, err := ParseKeyring(text)
+	if err != nil {
+		return nil, err
+	}
+	if k.primary == "" {
+		return nil, nil
+	}
+	return k, nil
+}
+
+// seal - шифрование основным ключом. aad связывает шифротекст с заголовком файла.
+func (k *Keyring) seal(plaintext, aad []byte) (nonce, ciphertext []byte, err error) {
+	gcm, err := newGCM(k.keys[k.primary])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, aad), nil
+}
+
+// SealValue - шифрование отдельного значения основным ключом, например записи во встроенной базе.
+// Результат содержит идентификатор ключа: длина (1 байт), идентификатор, nonce, шифротекст.
+func (k *Keyring) SealValue(plaintext, aad []byte) ([]byte, error) {
+	nonce, ciphertext, err := k.seal(plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 1+len(k.primary)+len(nonce)+len(ciphertext))
+	out = append(out, byte(len(k.primary)))
+	out = append(out, k.primary...)
+	out = append(out, nonce...)
+	return append(out, ciphertext...), nil
+}
+
+// OpenValue - расшифровка значения SealValue ключом из его заголовка.
+func (k *Keyring) OpenValue(value, aad []byte) ([]byte, error) {
+	if len(value) == 0 || len(value) < 1+int(value[0]) {
+		return nil, errors.New("decrypt value: truncated header")
+	}
+	keyID, rest := string(value[1:1+value[0]]), value[1+value[0]:]
+	if len(rest) < nonceSize {
+		return nil, errors.New("decrypt value: truncated nonce")
+	}
+	return k.open(keyID, rest[:nonceSize], rest[nonceSize:], aad)
+}
+
+// Digest - HMAC-SHA256 значения на основном ключе. Позволяет искать по зашифрованным данным
+// без хранения значения в открытом виде; при смене основного ключа меняется.
+func (k *Keyring) Digest(value []byte) []byte {
+	mac := hmac.New(sha256.New, k.keys[k.primary])
+	mac.Write([]byte("digest\x00"))
+	mac.Write(value)
+	return mac.Sum(nil)
+}
+
+// open - расшифровка ключом keyID.
+func (k *Keyring) open(keyID string, nonce, ciphertext, aad []byte) ([]byte, error) {
+	var key []byte
+	if k != nil {
+		key = k.keys[keyID]
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt storage file: %w", err)
+	}
+	return plaintext, nil
+}
+
+// newGCM - AES-GCM для ключа.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
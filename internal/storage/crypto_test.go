@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyring(t *testing.T, ids ...string) *Keyring {
+	t.Helper()
+
+	var lines []string
+	for _, id := range ids {
+		line, err := GenerateKey(id)
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+	keys, err := ParseKeyring(strings.Join(lines, "\n"))
+	require.NoError(t, err)
+	return keys
+}
+
+func TestEncryptedFile(t *testing.T) {
+	records := []Record{{ShortURL: "a", OriginalURL: "https://example.com/?token=secret"}}
+
+	t.Run("round trip", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "db.json")
+		keys := newTestKeyring(t, "k1")
+		require.NoError(t, WriteFile(file, records, FormatV2, keys))
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "secret")
+		require.Contains(t, string(data), `"key_id": "k1"`)
+
+		got, _, err := ReadFile(file, keys)
+		require.NoError(t, err)
+		require.Equal(t, records, got)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "db.json")
+		require.NoError(t, WriteFile(file, records, FormatV2, newTestKeyring(t, "k1")))
+
+		_, _, err := ReadFile(file, nil)
+		require.ErrorIs(t, err, ErrUnknownKey)
+
+		_, _, err = ReadFile(file, newTestKeyring(t, "k1"))
+		require.Error(t, err) // тот же идентификатор, другой ключ
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "db.json")
+		old := newTestKeyring(t, "k1")
+		require.NoError(t, WriteFile(file, records, FormatV2, old))
+
+		rotated := newTestKeyring(t, "k2")
+		rotated.Merge(old)
+		require.Equal(t, "k2", rotated.Primary())
+
+		db := New()
+		db.SetKeyring(rotated)
+		require.NoError(t, db.LoadFromFile(file))
+		require.NoError(t, db.SaveToFile(file))
+
+		_, _, err := ReadFile(file, old)
+		require.ErrorIs(t, err, ErrUnknownKey)
+		got, _, err := ReadFile(file, rotated)
+		require.NoError(t, err)
+		require.Equal(t, records, got)
+	})
+
+	t.Run("v1 cannot be encrypted", func(t *testing.T) {
+		err := WriteFile(filepath.Join(t.TempDir(), "db.json"), records, FormatV1, newTestKeyring(t, "k1"))
+		require.Error(t, err)
+	})
+}
+
+func TestSealValue(t *testing.T) {
+	keys := newTestKeyring(t, "k1")
+	sealed, err := keys.SealValue([]byte("https://example.com/?token=secret"), []byte("a"))
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed), "secret")
+
+	plain, err := keys.OpenValue(sealed, []byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/?token=secret", string(plain))
+
+	_, err = keys.OpenValue(sealed, []byte("b"))
+	require.Error(t, err) // значение привязано к своему ключу записи
+	_, err = keys.OpenValue(sealed[:4], []byte("a"))
+	require.Error(t, err)
+	_, err = newTestKeyring(t, "k2").OpenValue(sealed, []byte("a"))
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	require.Equal(t, keys.Digest([]byte("x")), keys.Digest([]byte("x")))
+	require.NotEqual(t, keys.Digest([]byte("x")), newTestKeyring(t, "k1").Digest([]byte("x")))
+}
+
+func TestLoadKeyring(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys")
+	k1, _ := GenerateKey("k1")
+	k2, _ := GenerateKey("k2")
+	require.NoError(t, os.WriteFile(keyFile, []byte("# keys\n"+k1+"\n"+k2+"\n"), 0600))
+
+	keys, err := LoadKeyring("", keyFile)
+	require.NoError(t, err)
+	require.Equal(t, "k1", keys.Primary())
+
+	k3, _ := GenerateKey("k3")
+	keys, err = LoadKeyring(k3, keyFile)
+	require.NoError(t, err)
+	require.Equal(t, "k3", keys.Primary())
+
+	keys, err = LoadKeyring("", "")
+	require.NoError(t, err)
+	require.Nil(t, keys)
+
+	_, err = LoadKeyring("k1:c2hvcnQ=", "")
+	require.Error(t, err)
+}
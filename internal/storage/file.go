@@ -0,0 +1,386 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Версии формата файла хранилища.
+const (
+	FormatV1 = 1 // JSON-массив записей
+	FormatV2 = 2 // заголовок с версией, количеством записей и контрольной суммой
+
+	CurrentFormat = FormatV2
+)
+
+var (
+	ErrEmptyFile = errors.New("empty file")
+	ErrChecksum  = errors.New("storage file checksum mismatch")
+	ErrProtected = errors.New("storage file failed to load, refusing to overwrite")
+)
+
+// checksumPrefix - алгоритм контрольной суммы в заголовке.
+const checksumPrefix = "sha256:"
+
+// record - запись об URLs.
+type record struct {
+	ID           int
+	ShortURL     string
+	OriginalURL  string
+	UserID       string            `json:",omitempty"`
+	DeletedFlag  bool              `json:",omitempty"`
+	Disabled     bool              `json:",omitempty"`
+	RedirectCode int               `json:",omitempty"`
+	ExpiresAt    time.Time         `json:",omitzero"`
+	Metadata     map[string]string `json:",omitempty"`
+	Version      int               `json:",omitempty"`
+	UpdatedAt    time.Time         `json:",omitzero"`
+	History      []Revision        `json:",omitempty"`
+	Title        string            `json:",omitempty"`
+	Tags         []string          `json:",omitempty"`
+	Notes        string            `json:",omitempty"`
+	CreatedAt    time.Time         `json:",omitzero"`
+	Clicks       int64             `json:",omitempty"`
+	PasswordHash string            `json:",omitempty"`
+	MaxClicks    int64             `json:",omitempty"`
+	NotBefore    time.Time         `json:",omitzero"`
+}
+
+// header - файл формата v2.
+// У зашифрованного файла records - строка base64 с шифротекстом массива записей,
+// а контрольная сумма считается по ней.
+type header struct {
+	Version    int             `json:"version"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Count      int             `json:"count"`
+	Checksum   string          `json:"checksum"`
+	Encryption string          `json:"encryption,omitempty"`
+	KeyID      string          `json:"key_id,omitempty"`
+	Nonce      []byte          `json:"nonce,omitempty"`
+	Records    json.RawMessage `json:"records"`
+}
+
+// aad - поля заголовка, защищённые шифрованием от подмены.
+func (h *header) aad() []byte {
+	return []byte(fmt.Sprintf("v%d|%s|%s|%d", h.Version, h.Encryption, h.KeyID, h.Count))
+}
+
+// SaveToFile - сохранение данных в JSON файл.
+// Файл, который не удалось загрузить, не перезаписывается без AllowOverwrite.
+func (db *DB) SaveToFile(filePath string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if db.protected[filepath.Clean(filePath)] {
+		return fmt.Errorf("%s: %w", filePath, ErrProtected)
+	}
+
+	return WriteFile(filePath, db.records(), CurrentFormat, db.keys)
+}
+
+// LoadFromFile - загрузка данных из JSON файла.
+// При ошибке разбора существующего файла он защищается от перезаписи.
+func (db *DB) LoadFromFile(filePath string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	records, _, err := ReadFile(filePath, db.keys)
+	if err != nil {
+		if !os.IsNotExist(err) && !errors.Is(err, ErrEmptyFile) {
+			db.protected[filepath.Clean(filePath)] = true
+		}
+		return err
+	}
+
+	data := make(map[string]Record)
+	urls := make(map[string]string)
+	for _, rec := range records {
+		data[rec.ShortURL] = rec
+		if !rec.DeletedFlag {
+			urls[rec.OriginalURL] = rec.ShortURL
+		}
+	}
+	idx := newIndex()
+	for _, rec := range data {
+		idx.add(rec)
+	}
+
+	db.data = data
+	db.urls = urls
+	db.index = idx
+	db.count = len(data)
+
+	return nil
+}
+
+// SetKeyring - шифрование файла хранилища. nil - файл хранится открытым.
+func (db *DB) SetKeyring(keys *Keyring) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	db.keys = keys
+}
+
+// AllowOverwrite - снятие защиты с файла, который не удалось загрузить.
+func (db *DB) AllowOverwrite(filePath string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(db.protected, filepath.Clean(filePath))
+}
+
+// ReadFile - чтение записей из файла хранилища как есть, вместе с версией формата.
+// Повторяющиеся ключи не схлопываются. При несовпадении контрольной суммы
+// или количества записей возвращаются прочитанные записи и ошибка ErrChecksum.
+// keys нужны только для зашифрованных файлов.
+func ReadFile(filePath string, keys *Keyring) ([]Record, int, error) {
+	if _, err := os.Stat(filePath); os.IsNotExist(err) { // файла не существует
+		return nil, 0, err
+	}
+
+	bytes, err := os.ReadFile(filePath) // ошибка чтения файла
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return decodeFile(bytes, keys)
+}
+
+// decodeFile - разбор содержимого файла любой поддерживаемой версии.
+func decodeFile(data []byte, keys *Keyring) ([]Record, int, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 { // пустой файл
+		return nil, 0, ErrEmptyFile
+	}
+
+	// Формат v1 - массив записей без заголовка
+	if data[0] == '[' || bytes.Equal(data, []byte("null")) {
+		records, err := decodeRecords(data)
+		return records, FormatV1, err
+	}
+
+	var h header
+	if err := json.Unmarshal(data, &h); err != nil { // ошибка десериализации JSON
+		return nil, 0, err
+	}
+	if h.Version != FormatV2 {
+		return nil, h.Version, fmt.Errorf("unsupported format version %d", h.Version)
+	}
+
+	plaintext := []byte(h.Records)
+	if h.Encryption != "" {
+		if h.Encryption != encryptionAlgorithm {
+			return nil, h.Version, fmt.Errorf("unsupported encryption %q", h.Encryption)
+		}
+
+		var encoded string
+		if err := json.Unmarshal(h.Records, &encoded); err != nil {
+			return nil, h.Version, err
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, h.Version, err
+		}
+		if plaintext, err = keys.open(h.KeyID, h.Nonce, ciphertext, h.aad()); err != nil {
+			return nil, h.Version, err
+		}
+	}
+
+	records, err := decodeRecords(plaintext)
+	if err != nil {
+		return nil, h.Version, err
+	}
+
+	sum, err := checksum(h.Records)
+	if err != nil {
+		return nil, h.Version, err
+	}
+	if sum != h.Checksum {
+		return records, h.Version, fmt.Errorf("%w: header %s, actual %s", ErrChecksum, h.Checksum, sum)
+	}
+	if len(records) != h.Count {
+		return records, h.Version, fmt.Errorf("%w: header count %d, actual %d", ErrChecksum, h.Count, len(records))
+	}
+
+	return records, h.Version, nil
+}
+
+// decodeRecords - разбор массива записей.
+func decodeRecords(data []byte) ([]Record, error) {
+	var records []record
+
+	if err := json.Unmarshal(data, &records); err != nil { // ошибка десериализации JSON
+		return nil, err
+	}
+
+	result := make([]Record, len(records))
+	for i, record := range records {
+		result[i] = Record{
+			ShortURL:     record.ShortURL,
+			OriginalURL:  record.OriginalURL,
+			UserID:       record.UserID,
+			DeletedFlag:  record.DeletedFlag,
+			Disabled:     record.Disabled,
+			RedirectCode: record.RedirectCode,
+			ExpiresAt:    record.ExpiresAt,
+			Metadata:     record.Metadata,
+			Version:      record.Version,
+			UpdatedAt:    record.UpdatedAt,
+			History:      record.History,
+			Title:        record.Title,
+			Tags:         record.Tags,
+			Notes:        record.Notes,
+			CreatedAt:    record.CreatedAt,
+			Clicks:       record.Clicks,
+			PasswordHash: record.PasswordHash,
+			MaxClicks:    record.MaxClicks,
+			NotBefore:    record.NotBefore,
+		}
+	}
+	return result, nil
+}
+
+// WriteFile - запись файла хранилища в заданной версии формата.
+// Если в keys есть основной ключ, записи шифруются им.
+func WriteFile(filePath string, records []Record, version int, keys *Keyring) error {
+	data, err := encodeFile(records, version, keys)
+	if err != nil {
+		return err
+	}
+
+	// Создаем директорию, если её нет
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Запись через временный файл, чтобы сбой не оставил файл недописанным.
+	// Данные сбрасываются на диск до переименования, а директория - после,
+	// иначе при отключении питания можно получить пустой файл или старую версию.
+	tmp := filePath + ".tmp"
+	if err := writeSynced(tmp, data); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(dir)
+}
+
+// writeSynced - запись data в файл с правами 0600 и сбросом на диск.
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir - сброс на диск записи директории, чтобы переименование пережило сбой.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// encodeFile - сериализация записей в заданной версии формата.
+func encodeFile(records []Record, version int, keys *Keyring) ([]byte, error) {
+	out := make([]record, len(records))
+	for i, rec := range records {
+		out[i] = record{
+			ID:           i + 1,
+			ShortURL:     rec.ShortURL,
+			OriginalURL:  rec.OriginalURL,
+			UserID:       rec.UserID,
+			DeletedFlag:  rec.DeletedFlag,
+			Disabled:     rec.Disabled,
+			RedirectCode: rec.RedirectCode,
+			ExpiresAt:    rec.ExpiresAt,
+			Metadata:     rec.Metadata,
+			Version:      rec.Version,
+			UpdatedAt:    rec.UpdatedAt,
+			History:      rec.History,
+			Title:        rec.Title,
+			Tags:         rec.Tags,
+			Notes:        rec.Notes,
+			CreatedAt:    rec.CreatedAt,
+			Clicks:       rec.Clicks,
+			PasswordHash: rec.PasswordHash,
+			MaxClicks:    rec.MaxClicks,
+			NotBefore:    rec.NotBefore,
+		}
+	}
+
+	switch version {
+	case FormatV1:
+		if keys.Primary() != "" {
+			return nil, fmt.Errorf("format v%d does not support encryption", version)
+		}
+		return json.MarshalIndent(out, "", "  ")
+
+	case FormatV2:
+		raw, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+
+		h := header{
+			Version:   FormatV2,
+			CreatedAt: time.Now().UTC(),
+			Count:     len(out),
+		}
+
+		if keys.Primary() != "" {
+			h.Encryption = encryptionAlgorithm
+			h.KeyID = keys.Primary()
+
+			nonce, ciphertext, err := keys.seal(raw, h.aad())
+			if err != nil {
+				return nil, err
+			}
+			h.Nonce = nonce
+			if raw, err = json.Marshal(base64.StdEncoding.EncodeToString(ciphertext)); err != nil {
+				return nil, err
+			}
+		}
+
+		h.Records = raw
+		if h.Checksum, err = checksum(raw); err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(h, "", "  ")
+	}
+
+	return nil, fmt.Errorf("unsupported format version %d", version)
+}
+
+// checksum - контрольная сумма массива записей, не зависящая от форматирования.
+func checksum(raw json.RawMessage) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return checksumPrefix + hex.EncodeToString(sum[:]), nil
+}
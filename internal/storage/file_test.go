@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWriteFile(t *testing.T) {
+	t.Run("keeps duplicate keys", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "db.json")
+		require.NoError(t, os.WriteFile(file, []byte(`[
+			{"ID":1,"ShortURL":"a","OriginalURL":"https://example.com"},
+			{"ID":2,"ShortURL":"a","OriginalURL":"https://google.com","UserID":"user1"}
+		]`), 0644))
+
+		records, version, err := ReadFile(file, nil)
+		require.NoError(t, err)
+		require.Equal(t, FormatV1, version)
+		require.Equal(t, []Record{
+			{ShortURL: "a", OriginalURL: "https://example.com"},
+			{ShortURL: "a", OriginalURL: "https://google.com", UserID: "user1"},
+		}, records)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "db.json")
+		records := []Record{
+			{ShortURL: "a", OriginalURL: "https://example.com", UserID: "user1", DeletedFlag: true},
+			{ShortURL: "b", OriginalURL: "https://google.com", Disabled: true},
+			{
+				ShortURL:     "c",
+				OriginalURL:  "https://example.org/v2",
+				RedirectCode: 301,
+				ExpiresAt:    time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+				Metadata:     map[string]string{"team": "growth"},
+				Version:      2,
+				UpdatedAt:    time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+				History: []Revision{
+					{Version: 1, OriginalURL: "https://example.org/v1", ReplacedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+				},
+				Title:        "Example",
+				Tags:         []string{"docs"},
+				Notes:        "Landing page",
+				CreatedAt:    time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+				Clicks:       42,
+				PasswordHash: "$2a$10$hash",
+				MaxClicks:    100,
+				NotBefore:    time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC),
+			},
+		}
+		require.NoError(t, WriteFile(file, records, FormatV1, nil))
+
+		got, _, err := ReadFile(file, nil)
+		require.NoError(t, err)
+		require.Equal(t, records, got)
+	})
+
+	t.Run("current format header", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "db.json")
+		require.NoError(t, WriteFile(file, []Record{{ShortURL: "a", OriginalURL: "https://example.com"}}, CurrentFormat, nil))
+
+		var h struct {
+			Version  int    `json:"version"`
+			Count    int    `json:"count"`
+			Checksum string `json:"checksum"`
+		}
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &h))
+		require.Equal(t, FormatV2, h.Version)
+		require.Equal(t, 1, h.Count)
+		require.True(t, strings.HasPrefix(h.Checksum, checksumPrefix))
+
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		require.NoFileExists(t, file+".tmp")
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "db.json")
+		require.NoError(t, WriteFile(file, []Record{{ShortURL: "a", OriginalURL: "https://example.com"}}, FormatV2, nil))
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		data = bytes.Replace(data, []byte("example"), []byte("exampel"), 1)
+		require.NoError(t, os.WriteFile(file, data, 0644))
+
+		records, _, err := ReadFile(file, nil)
+		require.ErrorIs(t, err, ErrChecksum)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		err := WriteFile(filepath.Join(t.TempDir(), "db.json"), nil, 99, nil)
+		require.Error(t, err)
+
+		file := filepath.Join(t.TempDir(), "db.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"version":99,"records":[]}`), 0644))
+		_, _, err = ReadFile(file, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestProtectedFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.json")
+	require.NoError(t, os.WriteFile(file, []byte("{corrupted"), 0644))
+
+	db := New()
+	require.Error(t, db.LoadFromFile(file))
+	db.Set("key", "https://example.com")
+
+	require.ErrorIs(t, db.SaveToFile(file), ErrProtected)
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "{corrupted", string(data))
+
+	db.AllowOverwrite(file)
+	require.NoError(t, db.SaveToFile(file))
+	require.NoError(t, New().LoadFromFile(file))
+}
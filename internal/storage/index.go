@@ -0,0 +1,94 @@
+package storage
+
+import "sort"
+
+// index - инвертированный индекс записей: владелец, слова заголовка и исходного URL и метки -> ключи.
+type index struct {
+	owners map[string]map[string]struct{}
+	words  map[string]map[string]struct{}
+	tags   map[string]map[string]struct{}
+}
+
+// newIndex - пустой индекс.
+func newIndex() *index {
+	return &index{
+		owners: make(map[string]map[string]struct{}),
+		words:  make(map[string]map[string]struct{}),
+		tags:   make(map[string]map[string]struct{}),
+	}
+}
+
+// add - добавление записи в индекс.
+func (idx *index) add(rec Record) {
+	insert(idx.owners, rec.UserID, rec.ShortURL)
+	for _, word := range recordWords(rec) {
+		insert(idx.words, word, rec.ShortURL)
+	}
+	for _, tag := range rec.Tags {
+		insert(idx.tags, tag, rec.ShortURL)
+	}
+}
+
+// remove - удаление записи из индекса.
+func (idx *index) remove(rec Record) {
+	discard(idx.owners, rec.UserID, rec.ShortURL)
+	for _, word := range recordWords(rec) {
+		discard(idx.words, word, rec.ShortURL)
+	}
+	for _, tag := range rec.Tags {
+		discard(idx.tags, tag, rec.ShortURL)
+	}
+}
+
+// lookup - ключи записей владельца owner, содержащих метку tag и все слова text.
+// false - условий нет, индекс выборку не сужает.
+func (idx *index) lookup(owner, tag, text string) ([]string, bool) {
+	var sets []map[string]struct{}
+	if owner != "" {
+		sets = append(sets, idx.owners[owner])
+	}
+	if tag != "" {
+		sets = append(sets, idx.tags[tag])
+	}
+	for _, term := range Terms(text) {
+		sets = append(sets, idx.words[term])
+	}
+	if len(sets) == 0 {
+		return nil, false
+	}
+
+	// Пересечение начинается с самого малого множества
+	sort.Slice(sets, func(i, j int) bool { return len(sets[i]) < len(sets[j]) })
+	var keys []string
+	for key := range sets[0] {
+		found := true
+		for _, set := range sets[1:] {
+			if _, found = set[key]; !found {
+				break
+			}
+		}
+		if found {
+			keys = append(keys, key)
+		}
+	}
+	return keys, true
+}
+
+// insert - добавление ключа в множество term.
+func insert(m map[string]map[string]struct{}, term, key string) {
+	set, exists := m[term]
+	if !exists {
+		set = make(map[string]struct{})
+		m[term] = set
+	}
+	set[key] = struct{}{}
+}
+
+// discard - удаление ключа из множества term. Пустое множество удаляется.
+func discard(m map[string]map[string]struct{}, term, key string) {
+	set := m[term]
+	delete(set, key)
+	if len(set) == 0 {
+		delete(m, term)
+	}
+}
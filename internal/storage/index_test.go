@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerms(t *testing.T) {
+	require.Equal(t, []string{"https", "example", "com", "summer", "sale"}, Terms("https://Example.com/summer-sale?summer"))
+	require.Equal(t, []string{"летняя", "распродажа"}, Terms("  Летняя РАСПРОДАЖА! "))
+	require.Empty(t, Terms(" - "))
+}
+
+func TestSearchIndex(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	require.NoError(t, db.Create(ctx, Record{ShortURL: "a", OriginalURL: "https://shop.example/summer", UserID: "user1", Title: "Summer sale", Tags: []string{"promo", "summer"}}))
+	require.NoError(t, db.Create(ctx, Record{ShortURL: "b", OriginalURL: "https://shop.example/winter", UserID: "user1", Title: "Winter sale", Tags: []string{"promo"}}))
+	require.NoError(t, db.Create(ctx, Record{ShortURL: "c", OriginalURL: "https://blog.example/summer", UserID: "user2", Tags: []string{"promo"}}))
+
+	keys := func(q Query) []string {
+		page, err := db.Search(ctx, q)
+		require.NoError(t, err)
+		var keys []string
+		for _, rec := range page.Records {
+			keys = append(keys, rec.ShortURL)
+		}
+		return keys
+	}
+
+	t.Run("tag and text", func(t *testing.T) {
+		require.Equal(t, []string{"a", "b", "c"}, keys(Query{Tag: "promo"}))
+		require.Equal(t, []string{"a", "c"}, keys(Query{Text: "SUMMER"}))
+		require.Equal(t, []string{"a", "b"}, keys(Query{Text: "sale shop"}))
+		require.Equal(t, []string{"a"}, keys(Query{Tag: "promo", Text: "summer", Owner: "user1"}))
+		require.Empty(t, keys(Query{Text: "summer winter"}))
+		require.Empty(t, keys(Query{Tag: "unknown"}))
+	})
+
+	t.Run("reindex on update", func(t *testing.T) {
+		_, err := db.Update(ctx, "b", func(rec *Record) error {
+			rec.Title = "Autumn sale"
+			rec.Tags = []string{"autumn"}
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.Empty(t, keys(Query{Tag: "promo", Text: "winter"}))
+		require.Equal(t, []string{"b"}, keys(Query{Text: "autumn"}))
+		require.Equal(t, []string{"a", "c"}, keys(Query{Tag: "promo"}))
+	})
+
+	t.Run("deleted records", func(t *testing.T) {
+		require.NoError(t, db.MarkDeleted(ctx, "c"))
+		require.Equal(t, []string{"a", "c"}, keys(Query{Text: "summer"}))
+		require.Equal(t, []string{"a"}, keys(Query{Text: "summer", Status: []string{StatusActive}}))
+
+		require.NoError(t, db.Delete("c"))
+		require.Equal(t, []string{"a"}, keys(Query{Text: "summer"}))
+	})
+
+	t.Run("rebuilt on load", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "db.json")
+		require.NoError(t, db.SaveToFile(file))
+
+		loaded := New()
+		require.NoError(t, loaded.LoadFromFile(file))
+		page, err := loaded.Search(ctx, Query{Tag: "summer", Text: "sale"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, "Summer sale", page.Records[0].Title)
+	})
+}
@@ -0,0 +1,626 @@
+// Package kv - встроенное дисковое хранилище ссылок на bbolt.
+// В отличие от storage.DB данные не держатся в памяти целиком и сохраняются при каждой записи.
+// С ключами шифрования записи хранятся зашифрованными, а индекс по URL - по HMAC от URL.
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Бакеты базы.
+var (
+	bucketLinks  = []byte("links")   // ключ -> запись
+	bucketByURL  = []byte("by_url")  // исходный URL -> ключ, только действующие записи
+	bucketByUser = []byte("by_user") // пользователь \x00 ключ -> пусто, только действующие записи
+	bucketMeta   = []byte("meta")
+
+	// Индексы порядка выдачи по всем записям, включая удалённые
+	bucketByCreated = []byte("by_created") // время создания \x00 ключ -> пусто
+	bucketByClicks  = []byte("by_clicks")  // число переходов (8 байт) ключ -> пусто
+)
+
+// createdLayout - формат времени создания в индексе: лексикографический порядок совпадает с хронологическим.
+const createdLayout = "20060102150405.000000000"
+
+// Ключи бакета meta.
+var (
+	metaActive   = []byte("active")   // число действующих записей
+	metaMigrated = []byte("migrated") // путь JSON-файла, из которого выполнена миграция
+	metaKey      = []byte("key")      // идентификатор ключа, которым зашифрованы записи
+)
+
+// sealedPrefix - признак зашифрованной записи, открытая запись начинается с "{".
+const sealedPrefix = 0
+
+// Индексы, которые перестраиваются по записям.
+var indexBuckets = [][]byte{bucketByURL, bucketByUser, bucketByCreated, bucketByClicks}
+
+// Store - хранилище на bbolt.
+type Store struct {
+	db   *bolt.DB
+	keys *storage.Keyring
+}
+
+// Option - настройка хранилища.
+type Option func(*Store)
+
+// WithKeyring - шифрование записей и снимков SaveToFile основным ключом keys.
+// nil - данные хранятся открытыми.
+func WithKeyring(keys *storage.Keyring) Option {
+	return func(s *Store) {
+		s.keys = keys
+	}
+}
+
+// Open - открытие или создание базы в файле path.
+// Если база зашифрована другим ключом или открыта, а ключ задан, записи перешифровываются
+// основным ключом. Освобождённые при этом страницы файла могут хранить прежние данные до перезаписи.
+func Open(path string, opts ...Option) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	s := &Store{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		// Базы, созданные до появления индексов порядка, индексируются при первом открытии
+		rebuild := tx.Bucket(bucketLinks) != nil && tx.Bucket(bucketByCreated) == nil
+
+		for _, name := range [][]byte{bucketLinks, bucketByURL, bucketByUser, bucketMeta, bucketByCreated, bucketByClicks} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		keyID := string(tx.Bucket(bucketMeta).Get(metaKey))
+		if keyID != "" && s.keys == nil {
+			return fmt.Errorf("database is encrypted with key %q, storage key is not configured", keyID)
+		}
+		if rebuild || keyID != s.keys.Primary() {
+			return s.rebuild(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// rebuild - перезапись всех записей текущим ключом и построение индексов заново.
+func (s *Store) rebuild(tx *bolt.Tx) error {
+	var records []storage.Record
+	err := tx.Bucket(bucketLinks).ForEach(func(k, v []byte) error {
+		rec, err := s.decode(k, v)
+		if err != nil {
+			return err
+		}
+		records = append(records, rec)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range indexBuckets {
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(bucketMeta).Delete(metaActive); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		if err := s.put(tx, rec); err != nil {
+			return err
+		}
+	}
+
+	if s.keys == nil {
+		return tx.Bucket(bucketMeta).Delete(metaKey)
+	}
+	return tx.Bucket(bucketMeta).Put(metaKey, []byte(s.keys.Primary()))
+}
+
+// Close - закрытие базы.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Find - поиск записи по ключу.
+func (s *Store) Find(_ context.Context, key string) (storage.Record, error) {
+	var rec storage.Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rec, err = s.get(tx, key)
+		return err
+	})
+	return rec, err
+}
+
+// FindByURL - поиск действующей записи по исходному URL.
+func (s *Store) FindByURL(_ context.Context, originalURL string) (storage.Record, error) {
+	var rec storage.Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(bucketByURL).Get(s.urlKey(originalURL))
+		if key == nil {
+			return storage.ErrNotFound
+		}
+		var err error
+		rec, err = s.get(tx, string(key))
+		return err
+	})
+	return rec, err
+}
+
+// FindByUser - действующие записи пользователя.
+func (s *Store) FindByUser(_ context.Context, userID string) ([]storage.Record, error) {
+	var records []storage.Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		prefix := userKey(userID, "")
+		c := tx.Bucket(bucketByUser).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			rec, err := s.get(tx, string(k[len(prefix):]))
+			if err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// Create - добавление новой записи. Ключ и действующий исходный URL должны быть уникальны.
+func (s *Store) Create(_ context.Context, rec storage.Record) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketLinks).Get([]byte(rec.ShortURL)) != nil {
+			return storage.ErrKeyExists
+		}
+		if !rec.DeletedFlag && tx.Bucket(bucketByURL).Get(s.urlKey(rec.OriginalURL)) != nil {
+			return storage.ErrURLExists
+		}
+		return s.put(tx, rec)
+	})
+}
+
+// MarkDeleted - пометка записи как удалённой. Запись остаётся в хранилище.
+func (s *Store) MarkDeleted(_ context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil {
+			return err
+		}
+		if err := s.unindex(tx, rec); err != nil {
+			return err
+		}
+		rec.DeletedFlag = true
+		return s.put(tx, rec)
+	})
+}
+
+// Update - атомарное изменение записи функцией fn в одной транзакции. Ключ записи не меняется.
+func (s *Store) Update(_ context.Context, key string, fn func(rec *storage.Record) error) (storage.Record, error) {
+	var rec storage.Record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		old, err := s.get(tx, key)
+		if err != nil {
+			return err
+		}
+
+		rec = old
+		if err := fn(&rec); err != nil {
+			return err
+		}
+		rec.ShortURL = key
+
+		if other := tx.Bucket(bucketByURL).Get(s.urlKey(rec.OriginalURL)); !rec.DeletedFlag && other != nil && string(other) != key {
+			return storage.ErrURLExists
+		}
+
+		if err := s.unindex(tx, old); err != nil {
+			return err
+		}
+		return s.put(tx, rec)
+	})
+	if err != nil {
+		return storage.Record{}, err
+	}
+	return rec, nil
+}
+
+// Search - страница записей, включая удалённые, по условиям q в порядке выдачи q.
+// Записи владельца отбираются по индексу by_user (если удалённые не нужны),
+// остальные запросы читают индекс порядка выдачи начиная с курсора и останавливаются на полной странице.
+func (s *Store) Search(_ context.Context, q storage.Query) (storage.Page, error) {
+	pager, err := storage.NewPager(q)
+	if err != nil {
+		return storage.Page{}, err
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		if q.Owner != "" && len(q.Status) > 0 && !slices.Contains(q.Status, storage.StatusDeleted) {
+			return s.searchUser(tx, q.Owner, pager)
+		}
+		return s.searchOrdered(tx, q, pager)
+	})
+	if err != nil {
+		return storage.Page{}, err
+	}
+	return pager.Page(), nil
+}
+
+// searchUser - отбор среди действующих записей владельца.
+func (s *Store) searchUser(tx *bolt.Tx, owner string, pager *storage.Pager) error {
+	prefix := userKey(owner, "")
+	c := tx.Bucket(bucketByUser).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		rec, err := s.get(tx, string(k[len(prefix):]))
+		if err != nil {
+			return err
+		}
+		pager.Add(rec)
+	}
+	return nil
+}
+
+// searchOrdered - обход индекса порядка выдачи от курсора до заполнения страницы.
+func (s *Store) searchOrdered(tx *bolt.Tx, q storage.Query, pager *storage.Pager) error {
+	// Индекс, ключ индекса для позиции и ключ записи из ключа индекса
+	bucket := tx.Bucket(bucketLinks)
+	indexKey := func(c storage.Cursor) []byte { return []byte(c.Key) }
+	recordKey := func(k []byte) string { return string(k) }
+	switch q.Sort {
+	case storage.SortCreated:
+		bucket, indexKey = tx.Bucket(bucketByCreated), createdKey
+		recordKey = func(k []byte) string { return string(k[len(createdLayout)+1:]) }
+	case storage.SortClicks:
+		bucket, indexKey = tx.Bucket(bucketByClicks), clicksKey
+		recordKey = func(k []byte) string { return string(k[8:]) }
+	}
+
+	c := bucket.Cursor()
+	var k []byte
+	switch {
+	case q.After == nil && q.Desc:
+		k, _ = c.Last()
+	case q.After == nil:
+		k, _ = c.First()
+	default:
+		pos := indexKey(*q.After)
+		k, _ = c.Seek(pos)
+		if q.Desc {
+			if k == nil {
+				k, _ = c.Last()
+			}
+			for k != nil && bytes.Compare(k, pos) >= 0 {
+				k, _ = c.Prev()
+			}
+		} else if bytes.Equal(k, pos) {
+			k, _ = c.Next()
+		}
+	}
+
+	for ; k != nil && !pager.Full(); k = step(c, q.Desc) {
+		rec, err := s.get(tx, recordKey(k))
+		if err != nil {
+			return err
+		}
+		pager.Add(rec)
+	}
+	return nil
+}
+
+// step - следующий ключ индекса в порядке выдачи.
+func step(c *bolt.Cursor, desc bool) []byte {
+	var k []byte
+	if desc {
+		k, _ = c.Prev()
+	} else {
+		k, _ = c.Next()
+	}
+	return k
+}
+
+// Click - учёт перехода по ссылке.
+func (s *Store) Click(_ context.Context, key string) (storage.Record, error) {
+	var rec storage.Record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if rec, err = s.get(tx, key); err != nil {
+			return err
+		}
+		if rec.Exhausted() {
+			return storage.ErrExhausted
+		}
+		if err := tx.Bucket(bucketByClicks).Delete(clicksKey(storage.Cursor{Clicks: rec.Clicks, Key: key})); err != nil {
+			return err
+		}
+		rec.Clicks++
+
+		data, err := s.encode(rec)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketLinks).Put([]byte(key), data); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketByClicks).Put(clicksKey(storage.Cursor{Clicks: rec.Clicks, Key: key}), nil)
+	})
+	if err != nil {
+		return storage.Record{}, err
+	}
+	return rec, nil
+}
+
+// Stats - статистика хранилища.
+func (s *Store) Stats(_ context.Context) (storage.Stats, error) {
+	var stats storage.Stats
+	err := s.db.View(func(tx *bolt.Tx) error {
+		stats.URLs = int(counter(tx, metaActive))
+
+		// Индекс упорядочен по пользователю, поэтому достаточно считать смену префикса
+		var last []byte
+		c := tx.Bucket(bucketByUser).Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			user, _, _ := bytes.Cut(k, []byte{0})
+			if len(user) > 0 && !bytes.Equal(user, last) {
+				stats.Users++
+				last = append(last[:0], user...)
+			}
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Records - все записи, включая удалённые, упорядоченные по ключу.
+func (s *Store) Records() ([]storage.Record, error) {
+	var records []storage.Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketLinks).ForEach(func(k, v []byte) error {
+			rec, err := s.decode(k, v)
+			if err != nil {
+				return err
+			}
+			records = append(records, rec)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// ForEachKey - обход ключей всех записей, включая удалённые.
+// Ключи собираются в одной транзакции, fn вызывается после её завершения.
+func (s *Store) ForEachKey(ctx context.Context, fn func(key string) error) error {
+	var keys []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketLinks).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveToFile - снимок всех записей в файл формата storage, например для резервной копии.
+func (s *Store) SaveToFile(filePath string) error {
+	records, err := s.Records()
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(filePath, records, storage.CurrentFormat, s.keys)
+}
+
+// Import - запись набора записей с заменой существующих в одной транзакции.
+func (s *Store) Import(records []storage.Record) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, rec := range records {
+			if old, err := s.get(tx, rec.ShortURL); err == nil {
+				if err := s.unindex(tx, old); err != nil {
+					return err
+				}
+			} else if !errors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+			if err := s.put(tx, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateFrom - однократный перенос данных из JSON-файла storage при первом запуске.
+// Возвращает число перенесённых записей; повторный вызов ничего не делает.
+func (s *Store) MigrateFrom(filePath string, keys *storage.Keyring) (int, error) {
+	var migrated bool
+	s.db.View(func(tx *bolt.Tx) error {
+		migrated = tx.Bucket(bucketMeta).Get(metaMigrated) != nil
+		return nil
+	})
+	if migrated {
+		return 0, nil
+	}
+
+	records, _, err := storage.ReadFile(filePath, keys)
+	if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, storage.ErrEmptyFile) {
+		return 0, fmt.Errorf("migrate from %s: %w", filePath, err)
+	}
+
+	// Повторяющиеся ключи схлопываются так же, как при загрузке storage.DB
+	if err := s.Import(records); err != nil {
+		return 0, err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketMeta).Put(metaMigrated, []byte(filePath))
+	})
+	return len(records), err
+}
+
+// get - чтение записи.
+func (s *Store) get(tx *bolt.Tx, key string) (storage.Record, error) {
+	data := tx.Bucket(bucketLinks).Get([]byte(key))
+	if data == nil {
+		return storage.Record{}, storage.ErrNotFound
+	}
+	return s.decode([]byte(key), data)
+}
+
+// encode - сериализация записи, с ключами - шифрование с привязкой к ключу записи.
+func (s *Store) encode(rec storage.Record) ([]byte, error) {
+	data, err := json.Marshal(rec)
+	if err != nil || s.keys == nil {
+		return data, err
+	}
+	sealed, err := s.keys.SealValue(data, []byte(rec.ShortURL))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{sealedPrefix}, sealed...), nil
+}
+
+// decode - чтение записи, открытой или зашифрованной любым ключом набора.
+func (s *Store) decode(key, data []byte) (storage.Record, error) {
+	var rec storage.Record
+	if len(data) > 0 && data[0] == sealedPrefix {
+		var err error
+		if data, err = s.keys.OpenValue(data[1:], key); err != nil {
+			return rec, fmt.Errorf("record %q: %w", key, err)
+		}
+	}
+	err := json.Unmarshal(data, &rec)
+	return rec, err
+}
+
+// urlKey - ключ индекса by_url: с ключами шифрования HMAC от URL, чтобы URL не хранился открытым.
+func (s *Store) urlKey(originalURL string) []byte {
+	if s.keys == nil {
+		return []byte(originalURL)
+	}
+	return s.keys.Digest([]byte(originalURL))
+}
+
+// put - запись с обновлением индексов. Старая версия записи должна быть уже снята с индексов.
+func (s *Store) put(tx *bolt.Tx, rec storage.Record) error {
+	data, err := s.encode(rec)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketLinks).Put([]byte(rec.ShortURL), data); err != nil {
+		return err
+	}
+	if err := putOrder(tx, rec); err != nil {
+		return err
+	}
+
+	if rec.DeletedFlag {
+		return nil
+	}
+	if err := tx.Bucket(bucketByURL).Put(s.urlKey(rec.OriginalURL), []byte(rec.ShortURL)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketByUser).Put(userKey(rec.UserID, rec.ShortURL), nil); err != nil {
+		return err
+	}
+	return addCounter(tx, metaActive, 1)
+}
+
+// unindex - снятие записи с индексов.
+func (s *Store) unindex(tx *bolt.Tx, rec storage.Record) error {
+	pos := storage.Cursor{Created: rec.CreatedAt, Clicks: rec.Clicks, Key: rec.ShortURL}
+	if err := tx.Bucket(bucketByCreated).Delete(createdKey(pos)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketByClicks).Delete(clicksKey(pos)); err != nil {
+		return err
+	}
+
+	if rec.DeletedFlag {
+		return nil
+	}
+
+	byURL, urlKey := tx.Bucket(bucketByURL), s.urlKey(rec.OriginalURL)
+	if key := byURL.Get(urlKey); string(key) == rec.ShortURL {
+		if err := byURL.Delete(urlKey); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(bucketByUser).Delete(userKey(rec.UserID, rec.ShortURL)); err != nil {
+		return err
+	}
+	return addCounter(tx, metaActive, -1)
+}
+
+// putOrder - добавление записи в индексы порядка выдачи.
+func putOrder(tx *bolt.Tx, rec storage.Record) error {
+	pos := storage.Cursor{Created: rec.CreatedAt, Clicks: rec.Clicks, Key: rec.ShortURL}
+	if err := tx.Bucket(bucketByCreated).Put(createdKey(pos), nil); err != nil {
+		return err
+	}
+	return tx.Bucket(bucketByClicks).Put(clicksKey(pos), nil)
+}
+
+// createdKey - ключ индекса по времени создания.
+func createdKey(c storage.Cursor) []byte {
+	return []byte(c.Created.UTC().Format(createdLayout) + "\x00" + c.Key)
+}
+
+// clicksKey - ключ индекса по числу переходов.
+func clicksKey(c storage.Cursor) []byte {
+	k := binary.BigEndian.AppendUint64(nil, uint64(c.Clicks))
+	return append(k, c.Key...)
+}
+
+// userKey - ключ индекса по пользователю.
+func userKey(userID, key string) []byte {
+	return []byte(userID + "\x00" + key)
+}
+
+// counter - значение счётчика из meta.
+func counter(tx *bolt.Tx, name []byte) int64 {
+	data := tx.Bucket(bucketMeta).Get(name)
+	if len(data) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(data))
+}
+
+// addCounter - изменение счётчика в meta.
+func addCounter(tx *bolt.Tx, name []byte, delta int64) error {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(counter(tx, name)+delta))
+	return tx.Bucket(bucketMeta).Put(name, data)
+}
@@ -0,0 +1,412 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "db.bolt"))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create and find", func(t *testing.T) {
+		s := newTestStore(t)
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: "https://example.com", UserID: "user1"}))
+
+		rec, err := s.Find(ctx, "key1")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com", rec.OriginalURL)
+
+		rec, err = s.FindByURL(ctx, "https://example.com")
+		require.NoError(t, err)
+		require.Equal(t, "key1", rec.ShortURL)
+
+		_, err = s.Find(ctx, "nonexistent")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("uniqueness", func(t *testing.T) {
+		s := newTestStore(t)
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: "https://example.com"}))
+
+		err := s.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: "https://google.com"})
+		require.ErrorIs(t, err, storage.ErrKeyExists)
+
+		err = s.Create(ctx, storage.Record{ShortURL: "key2", OriginalURL: "https://example.com"})
+		require.ErrorIs(t, err, storage.ErrURLExists)
+	})
+
+	t.Run("user index and stats", func(t *testing.T) {
+		s := newTestStore(t)
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "a", OriginalURL: "https://a.example", UserID: "user1"}))
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "b", OriginalURL: "https://b.example", UserID: "user1"}))
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "c", OriginalURL: "https://c.example", UserID: "user10"}))
+
+		records, err := s.FindByUser(ctx, "user1")
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		stats, err := s.Stats(ctx)
+		require.NoError(t, err)
+		require.Equal(t, storage.Stats{URLs: 3, Users: 2}, stats)
+
+		require.NoError(t, s.MarkDeleted(ctx, "c"))
+		rec, err := s.Find(ctx, "c")
+		require.NoError(t, err)
+		require.True(t, rec.DeletedFlag)
+
+		_, err = s.FindByURL(ctx, "https://c.example")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		stats, err = s.Stats(ctx)
+		require.NoError(t, err)
+		require.Equal(t, storage.Stats{URLs: 2, Users: 1}, stats)
+
+		var keys []string
+		require.NoError(t, s.ForEachKey(ctx, func(key string) error {
+			keys = append(keys, key)
+			return nil
+		}))
+		require.Equal(t, []string{"a", "b", "c"}, keys)
+	})
+
+	t.Run("search user links", func(t *testing.T) {
+		s := newTestStore(t)
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "a", OriginalURL: "https://a.example", UserID: "user1", Title: "Summer sale", Tags: []string{"promo"}}))
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "b", OriginalURL: "https://b.example", UserID: "user1", Tags: []string{"promo"}}))
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "c", OriginalURL: "https://c.example", UserID: "user2", Title: "Summer", Tags: []string{"promo"}}))
+		require.NoError(t, s.MarkDeleted(ctx, "b"))
+
+		page, err := s.Search(ctx, storage.Query{Owner: "user1", Tag: "promo", Status: []string{storage.StatusActive}})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, "a", page.Records[0].ShortURL)
+		require.Equal(t, []string{"promo"}, page.Records[0].Tags)
+
+		page, err = s.Search(ctx, storage.Query{Text: "summer"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 2)
+	})
+
+	t.Run("update and search", func(t *testing.T) {
+		s := newTestStore(t)
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "a", OriginalURL: "https://a.example", UserID: "user1"}))
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "b", OriginalURL: "https://b.example", UserID: "user1"}))
+
+		rec, err := s.Update(ctx, "a", func(rec *storage.Record) error {
+			rec.UserID = "user2"
+			rec.Disabled = true
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, storage.Record{ShortURL: "a", OriginalURL: "https://a.example", UserID: "user2", Disabled: true}, rec)
+
+		records, err := s.FindByUser(ctx, "user2")
+		require.NoError(t, err)
+		require.Equal(t, []storage.Record{rec}, records)
+
+		stats, err := s.Stats(ctx)
+		require.NoError(t, err)
+		require.Equal(t, storage.Stats{URLs: 2, Users: 2}, stats)
+
+		_, err = s.Update(ctx, "b", func(rec *storage.Record) error {
+			rec.OriginalURL = "https://a.example"
+			return nil
+		})
+		require.ErrorIs(t, err, storage.ErrURLExists)
+
+		_, err = s.Update(ctx, "nonexistent", func(rec *storage.Record) error { return nil })
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		page, err := s.Search(ctx, storage.Query{URL: "example", Owner: "user1"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, "b", page.Records[0].ShortURL)
+
+		page, err = s.Search(ctx, storage.Query{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, "a", page.Records[0].ShortURL)
+	})
+
+	t.Run("persists across reopen", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db.bolt")
+		s, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: "https://example.com"}))
+		require.NoError(t, s.Close())
+
+		s, err = Open(path)
+		require.NoError(t, err)
+		defer s.Close()
+		_, err = s.Find(ctx, "key1")
+		require.NoError(t, err)
+	})
+}
+
+func TestMigrateFrom(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "db.json")
+
+	db := storage.New()
+	db.Put(storage.Record{ShortURL: "a", OriginalURL: "https://a.example", UserID: "user1"})
+	db.Put(storage.Record{ShortURL: "b", OriginalURL: "https://b.example", DeletedFlag: true})
+	require.NoError(t, db.SaveToFile(jsonFile))
+
+	s := newTestStore(t)
+	migrated, err := s.MigrateFrom(jsonFile, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, migrated)
+
+	records, err := s.FindByUser(ctx, "user1")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	rec, err := s.Find(ctx, "b")
+	require.NoError(t, err)
+	require.True(t, rec.DeletedFlag)
+
+	// Повторная миграция не выполняется
+	migrated, err = s.MigrateFrom(jsonFile, nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, migrated)
+
+	// Снимок читается как обычный файл хранилища
+	snapshot := filepath.Join(dir, "snapshot.json")
+	require.NoError(t, s.SaveToFile(snapshot))
+	restored := storage.New()
+	require.NoError(t, restored.LoadFromFile(snapshot))
+	require.Equal(t, db.Records(), restored.Records())
+}
+
+func TestSearchPages(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// Выдача по индексам bbolt должна совпадать с выдачей storage.DB
+	s := newTestStore(t)
+	db := storage.New()
+	for i := range 12 {
+		rec := storage.Record{
+			ShortURL:    fmt.Sprintf("k%02d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+			UserID:      fmt.Sprintf("user%d", i%2),
+			CreatedAt:   base.Add(time.Duration(i*7%12) * time.Hour),
+			DeletedFlag: i%5 == 0,
+		}
+		require.NoError(t, s.Create(ctx, rec))
+		require.NoError(t, db.Create(ctx, rec))
+	}
+	for i := range 12 {
+		for range i % 4 {
+			_, err := s.Click(ctx, fmt.Sprintf("k%02d", i))
+			require.NoError(t, err)
+			_, err = db.Click(ctx, fmt.Sprintf("k%02d", i))
+			require.NoError(t, err)
+		}
+	}
+
+	all := func(repo storage.Repository, q storage.Query) []string {
+		var keys []string
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 20)
+			page, err := repo.Search(ctx, q)
+			require.NoError(t, err)
+			for _, rec := range page.Records {
+				keys = append(keys, rec.ShortURL)
+			}
+			if page.Next == nil {
+				return keys
+			}
+			q.After = page.Next
+		}
+	}
+
+	for _, sort := range []string{storage.SortKey, storage.SortCreated, storage.SortClicks} {
+		for _, desc := range []bool{false, true} {
+			for _, q := range []storage.Query{
+				{Limit: 5},
+				{Limit: 2, Status: []string{storage.StatusDeleted}},
+				{Limit: 3, Owner: "user1", Status: []string{storage.StatusActive}},
+				{Limit: 4, From: base.Add(2 * time.Hour), To: base.Add(9 * time.Hour)},
+			} {
+				q.Sort, q.Desc = sort, desc
+				want := all(db, q)
+				require.NotEmpty(t, want)
+				require.Equal(t, want, all(s, q), "%+v", q)
+			}
+		}
+	}
+
+	t.Run("click", func(t *testing.T) {
+		rec, err := s.Click(ctx, "k03")
+		require.NoError(t, err)
+		require.Equal(t, int64(4), rec.Clicks)
+
+		page, err := s.Search(ctx, storage.Query{Sort: storage.SortClicks, Desc: true, Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, "k03", page.Records[0].ShortURL)
+
+		_, err = s.Click(ctx, "unknown")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func TestClickLimit(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.bolt")
+	s, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "once", OriginalURL: "https://example.org", MaxClicks: 5}))
+
+	var wg sync.WaitGroup
+	var allowed atomic.Int64
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Click(ctx, "once"); err == nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int64(5), allowed.Load())
+	require.NoError(t, s.Close())
+
+	// Счётчик переходов сохраняется между запусками
+	s, err = Open(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	rec, err := s.Find(ctx, "once")
+	require.NoError(t, err)
+	require.Equal(t, int64(5), rec.Clicks)
+	_, err = s.Click(ctx, "once")
+	require.ErrorIs(t, err, storage.ErrExhausted)
+}
+
+func TestOrderIndexRebuild(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.bolt")
+	s, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "a", OriginalURL: "https://a.example", CreatedAt: time.Unix(200, 0)}))
+	require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "b", OriginalURL: "https://b.example", CreatedAt: time.Unix(100, 0)}))
+
+	// База без индексов порядка, как до их появления
+	require.NoError(t, s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(bucketByCreated); err != nil {
+			return err
+		}
+		return tx.DeleteBucket(bucketByClicks)
+	}))
+	require.NoError(t, s.Close())
+
+	s, err = Open(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	page, err := s.Search(ctx, storage.Query{Sort: storage.SortCreated})
+	require.NoError(t, err)
+	require.Len(t, page.Records, 2)
+	require.Equal(t, "b", page.Records[0].ShortURL)
+}
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	newKeyring := func(ids ...string) *storage.Keyring {
+		var text string
+		for _, id := range ids {
+			line, err := storage.GenerateKey(id)
+			require.NoError(t, err)
+			text += line + "\n"
+		}
+		keys, err := storage.ParseKeyring(text)
+		require.NoError(t, err)
+		return keys
+	}
+	const secretURL = "https://secret.example/private/report"
+
+	t.Run("records are not stored in plaintext", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db.bolt")
+		keys := newKeyring("k1")
+		s, err := Open(path, WithKeyring(keys))
+		require.NoError(t, err)
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: secretURL, UserID: "user1"}))
+
+		rec, err := s.FindByURL(ctx, secretURL)
+		require.NoError(t, err)
+		require.Equal(t, "key1", rec.ShortURL)
+		require.NoError(t, s.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), secretURL)
+		require.NotContains(t, string(data), "secret.example")
+
+		_, err = Open(path)
+		require.ErrorContains(t, err, `encrypted with key "k1"`)
+
+		s, err = Open(path, WithKeyring(keys))
+		require.NoError(t, err)
+		defer s.Close()
+		rec, err = s.Find(ctx, "key1")
+		require.NoError(t, err)
+		require.Equal(t, secretURL, rec.OriginalURL)
+	})
+
+	t.Run("existing database is encrypted on open", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db.bolt")
+		s, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, s.Create(ctx, storage.Record{ShortURL: "key1", OriginalURL: secretURL, UserID: "user1"}))
+		require.NoError(t, s.Close())
+
+		old := newKeyring("k1")
+		s, err = Open(path, WithKeyring(old))
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		// Ротация: новый основной ключ, старый остаётся для чтения
+		rotated := newKeyring("k2")
+		rotated.Merge(old)
+		s, err = Open(path, WithKeyring(rotated))
+		require.NoError(t, err)
+		defer s.Close()
+
+		rec, err := s.FindByURL(ctx, secretURL)
+		require.NoError(t, err)
+		require.Equal(t, "key1", rec.ShortURL)
+		require.NoError(t, s.db.View(func(tx *bolt.Tx) error {
+			require.Equal(t, "k2", string(tx.Bucket(bucketMeta).Get(metaKey)))
+			require.Nil(t, tx.Bucket(bucketByURL).Get([]byte(secretURL)))
+			return nil
+		}))
+
+		records, err := s.FindByUser(ctx, "user1")
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		stats, err := s.Stats(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, stats.URLs)
+	})
+}
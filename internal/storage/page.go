@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"cmp"
+	"container/heap"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Порядок выдачи поиска.
+const (
+	SortKey     = "key"
+	SortCreated = "created_at"
+	SortClicks  = "clicks"
+)
+
+// Состояния записей.
+const (
+	StatusActive    = "active"
+	StatusScheduled = "scheduled"
+	StatusExpired   = "expired"
+	StatusDeleted   = "deleted"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor - позиция в выдаче: значение поля сортировки и ключ последней выданной записи.
+type Cursor struct {
+	Sort    string    `json:"s"`
+	Desc    bool      `json:"d,omitempty"`
+	Created time.Time `json:"c,omitzero"`
+	Clicks  int64     `json:"n,omitempty"`
+	Key     string    `json:"k"`
+}
+
+// String - непрозрачное представление курсора для клиента.
+func (c Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor - разбор курсора, полученного от клиента.
+func ParseCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || !slices.Contains([]string{SortKey, SortCreated, SortClicks}, c.Sort) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Page - страница выдачи. Next - курсор следующей страницы, nil - страница последняя.
+type Page struct {
+	Records []Record
+	Next    *Cursor
+}
+
+// Status - состояние записи на момент now. Ссылка с исчерпанным числом переходов считается истёкшей.
+func Status(rec Record, now time.Time) string {
+	switch {
+	case rec.DeletedFlag:
+		return StatusDeleted
+	case !rec.NotBefore.IsZero() && now.Before(rec.NotBefore):
+		return StatusScheduled
+	case !rec.ExpiresAt.IsZero() && !now.Before(rec.ExpiresAt), rec.Exhausted():
+		return StatusExpired
+	default:
+		return StatusActive
+	}
+}
+
+// sort - порядок выдачи с учётом значения по умолчанию.
+func (q Query) sort() string {
+	if q.Sort == "" {
+		return SortKey
+	}
+	return q.Sort
+}
+
+// Position - позиция записи в порядке выдачи q.
+func (q Query) Position(rec Record) Cursor {
+	c := Cursor{Sort: q.sort(), Desc: q.Desc, Key: rec.ShortURL}
+	switch c.Sort {
+	case SortCreated:
+		c.Created = rec.CreatedAt
+	case SortClicks:
+		c.Clicks = rec.Clicks
+	}
+	return c
+}
+
+// Compare - сравнение позиций в порядке выдачи q. Равные значения поля сортировки упорядочиваются по ключу.
+func (q Query) Compare(a, b Cursor) int {
+	var res int
+	switch q.sort() {
+	case SortCreated:
+		res = a.Created.Compare(b.Created)
+	case SortClicks:
+		res = cmp.Compare(a.Clicks, b.Clicks)
+	}
+	if res == 0 {
+		res = strings.Compare(a.Key, b.Key)
+	}
+	if q.Desc {
+		return -res
+	}
+	return res
+}
+
+// Pager - отбор страницы выдачи. Хранит не больше Limit+1 лучших записей,
+// поэтому записи не нужно ни сортировать целиком, ни подавать упорядоченными.
+type Pager struct {
+	q    Query
+	heap pagerHeap
+}
+
+// NewPager - отбор страницы по условиям q. Курсор должен относиться к тому же порядку выдачи.
+func NewPager(q Query) (*Pager, error) {
+	if q.After != nil && (q.After.Sort != q.sort() || q.After.Desc != q.Desc) {
+		return nil, ErrInvalidCursor
+	}
+	if q.Now.IsZero() {
+		q.Now = time.Now()
+	}
+	return &Pager{q: q, heap: pagerHeap{q: q}}, nil
+}
+
+// Add - учёт записи: подходит ли она под условия и стоит ли после курсора.
+func (p *Pager) Add(rec Record) {
+	pos := p.q.Position(rec)
+	if !p.q.Match(rec) || (p.q.After != nil && p.q.Compare(pos, *p.q.After) <= 0) {
+		return
+	}
+
+	if p.q.Limit <= 0 || p.heap.Len() <= p.q.Limit {
+		heap.Push(&p.heap, rec)
+		return
+	}
+	// Куча полна - запись заменяет худшую, если стоит раньше неё
+	if p.q.Compare(pos, p.q.Position(p.heap.records[0])) < 0 {
+		p.heap.records[0] = rec
+		heap.Fix(&p.heap, 0)
+	}
+}
+
+// Full - набрано Limit+1 записей. Если записи подаются в порядке выдачи, остальные можно не читать.
+func (p *Pager) Full() bool {
+	return p.q.Limit > 0 && p.heap.Len() > p.q.Limit
+}
+
+// Page - отобранная страница в порядке выдачи.
+func (p *Pager) Page() Page {
+	records := p.heap.records
+	slices.SortFunc(records, func(a, b Record) int { return p.q.Compare(p.q.Position(a), p.q.Position(b)) })
+
+	var page Page
+	if p.q.Limit > 0 && len(records) > p.q.Limit {
+		records = records[:p.q.Limit]
+		next := p.q.Position(records[len(records)-1])
+		page.Next = &next
+	}
+	page.Records = records
+	return page
+}
+
+// pagerHeap - куча записей, в вершине худшая в порядке выдачи.
+type pagerHeap struct {
+	q       Query
+	records []Record
+}
+
+func (h pagerHeap) Len() int { return len(h.records) }
+
+func (h pagerHeap) Less(i, j int) bool {
+	return h.q.Compare(h.q.Position(h.records[i]), h.q.Position(h.records[j])) > 0
+}
+
+func (h pagerHeap) Swap(i, j int) { h.records[i], h.records[j] = h.records[j], h.records[i] }
+
+func (h *pagerHeap) Push(x any) { h.records = append(h.records, x.(Record)) }
+
+func (h *pagerHeap) Pop() any {
+	rec := h.records[len(h.records)-1]
+	h.records = h.records[:len(h.records)-1]
+	return rec
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	c := Cursor{Sort: SortCreated, Desc: true, Created: time.Date(2025, 6, 1, 0, 0, 0, 5, time.UTC), Key: "abc"}
+	parsed, err := ParseCursor(c.String())
+	require.NoError(t, err)
+	require.Equal(t, c, parsed)
+
+	for _, raw := range []string{"", "!!!", "e30", Cursor{Sort: "title"}.String()} {
+		_, err := ParseCursor(raw)
+		require.ErrorIs(t, err, ErrInvalidCursor, raw)
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	db := New()
+	for i := range 10 {
+		require.NoError(t, db.Create(ctx, Record{
+			ShortURL:    fmt.Sprintf("k%02d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+			UserID:      "user1",
+			CreatedAt:   base.Add(time.Duration(9-i) * time.Hour), // k09 - самая старая
+		}))
+	}
+	for range 3 {
+		_, err := db.Click(ctx, "k05")
+		require.NoError(t, err)
+	}
+	_, err := db.Click(ctx, "k07")
+	require.NoError(t, err)
+
+	// all - ключи всех страниц выдачи
+	all := func(q Query) []string {
+		var keys []string
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 20)
+			page, err := db.Search(ctx, q)
+			require.NoError(t, err)
+			for _, rec := range page.Records {
+				keys = append(keys, rec.ShortURL)
+			}
+			if page.Next == nil {
+				return keys
+			}
+			q.After = page.Next
+		}
+	}
+
+	t.Run("by key", func(t *testing.T) {
+		require.Equal(t, []string{"k00", "k01", "k02", "k03", "k04", "k05", "k06", "k07", "k08", "k09"}, all(Query{Limit: 3}))
+		require.Equal(t, []string{"k09", "k08", "k07"}, all(Query{Limit: 2, Desc: true, Key: "k0", From: base.Add(-time.Minute), To: base.Add(3 * time.Hour)}))
+	})
+
+	t.Run("by created", func(t *testing.T) {
+		require.Equal(t, []string{"k09", "k08", "k07", "k06", "k05", "k04", "k03", "k02", "k01", "k00"}, all(Query{Sort: SortCreated, Limit: 4}))
+		require.Equal(t, []string{"k00", "k01", "k02"}, all(Query{Sort: SortCreated, Desc: true, Limit: 2, From: base.Add(7 * time.Hour)}))
+	})
+
+	t.Run("by clicks", func(t *testing.T) {
+		keys := all(Query{Sort: SortClicks, Desc: true, Limit: 3})
+		require.Len(t, keys, 10)
+		require.Equal(t, []string{"k05", "k07", "k09", "k08"}, keys[:4])
+	})
+
+	t.Run("status", func(t *testing.T) {
+		require.NoError(t, db.MarkDeleted(ctx, "k01"))
+		_, err := db.Update(ctx, "k02", func(rec *Record) error {
+			rec.ExpiresAt = base
+			return nil
+		})
+		require.NoError(t, err)
+
+		now := base.Add(time.Hour)
+		require.Equal(t, []string{"k01"}, all(Query{Status: []string{StatusDeleted}, Now: now, Limit: 5}))
+		require.Equal(t, []string{"k02"}, all(Query{Status: []string{StatusExpired}, Now: now, Limit: 5}))
+		require.Len(t, all(Query{Status: []string{StatusActive, StatusExpired}, Now: now, Limit: 5}), 9)
+		// До истечения срока ссылка действующая
+		require.Len(t, all(Query{Status: []string{StatusActive}, Now: base.Add(-time.Hour), Limit: 5}), 9)
+	})
+
+	t.Run("cursor of another order", func(t *testing.T) {
+		page, err := db.Search(ctx, Query{Sort: SortClicks, Limit: 1})
+		require.NoError(t, err)
+		_, err = db.Search(ctx, Query{Sort: SortCreated, After: page.Next})
+		require.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestStatus(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		rec  Record
+		want string
+	}{
+		{name: "active", rec: Record{}, want: StatusActive},
+		{name: "window open", rec: Record{NotBefore: now, ExpiresAt: now.Add(time.Hour)}, want: StatusActive},
+		{name: "scheduled", rec: Record{NotBefore: now.Add(time.Second)}, want: StatusScheduled},
+		{name: "expired", rec: Record{ExpiresAt: now}, want: StatusExpired},
+		{name: "exhausted", rec: Record{MaxClicks: 1, Clicks: 1}, want: StatusExpired},
+		{name: "deleted", rec: Record{DeletedFlag: true, NotBefore: now.Add(time.Hour)}, want: StatusDeleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Status(tt.rec, now))
+		})
+	}
+}
+
+func TestClick(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	db.Set("a", "https://example.com")
+
+	rec, err := db.Click(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rec.Clicks)
+
+	_, err = db.Click(ctx, "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	t.Run("limit under concurrency", func(t *testing.T) {
+		db.Put(Record{ShortURL: "once", OriginalURL: "https://example.org", MaxClicks: 5})
+
+		var wg sync.WaitGroup
+		var allowed atomic.Int64
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := db.Click(ctx, "once"); err == nil {
+					allowed.Add(1)
+				} else {
+					require.ErrorIs(t, err, ErrExhausted)
+				}
+			}()
+		}
+		wg.Wait()
+
+		require.Equal(t, int64(5), allowed.Load())
+		rec, err := db.Find(ctx, "once")
+		require.NoError(t, err)
+		require.Equal(t, int64(5), rec.Clicks)
+		require.Equal(t, StatusExpired, Status(rec, time.Now()))
+	})
+}
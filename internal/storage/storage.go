@@ -1,25 +1,187 @@
 package storage
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+	"unicode"
 )
 
 var mutex sync.Mutex
 
+var (
+	ErrNotFound  = errors.New("key not found")
+	ErrKeyExists = errors.New("key already exists")
+	ErrURLExists = errors.New("url already exists")
+	ErrExhausted = errors.New("click limit reached")
+)
+
+// Record - сокращённая ссылка.
+type Record struct {
+	ShortURL     string
+	OriginalURL  string
+	UserID       string
+	DeletedFlag  bool
+	Disabled     bool              // отключена модератором
+	RedirectCode int               // код перенаправления, 0 - 307
+	ExpiresAt    time.Time         // срок действия, нулевой - бессрочно
+	Metadata     map[string]string // произвольные метаданные владельца
+	Version      int               // номер версии, растёт при каждом изменении владельцем или администратором
+	UpdatedAt    time.Time         // время последнего изменения
+	History      []Revision        // предыдущие версии, от старых к новым
+	Title        string            // заголовок
+	Tags         []string          // метки в нижнем регистре, без повторов
+	Notes        string            // заметки владельца
+	CreatedAt    time.Time         // время создания
+	Clicks       int64             // число переходов
+	PasswordHash string            // bcrypt-хеш пароля доступа, пустой - ссылка открыта
+	MaxClicks    int64             // допустимое число переходов, 0 - без ограничения
+	NotBefore    time.Time         // начало действия, нулевое - сразу после создания
+}
+
+// Exhausted - допустимое число переходов исчерпано.
+func (rec Record) Exhausted() bool {
+	return rec.MaxClicks > 0 && rec.Clicks >= rec.MaxClicks
+}
+
+// Revision - предыдущая версия цели ссылки.
+type Revision struct {
+	Version      int
+	OriginalURL  string
+	RedirectCode int       `json:",omitempty"`
+	ExpiresAt    time.Time `json:",omitzero"`
+	ReplacedAt   time.Time // время замены следующей версией
+}
+
+// Query - условия поиска записей и порядок выдачи. Пустые условия не ограничивают выборку.
+type Query struct {
+	Key    string    // подстрока ключа
+	URL    string    // подстрока исходного URL
+	Owner  string    // идентификатор владельца
+	Tag    string    // метка
+	Text   string    // слова, каждое из которых должно встречаться в заголовке или исходном URL
+	Status []string  // допустимые состояния записей: StatusActive, StatusScheduled, StatusExpired, StatusDeleted
+	From   time.Time // создана не раньше
+	To     time.Time // создана раньше
+	Now    time.Time // момент, на который определяется истечение срока, нулевой - текущий
+	Sort   string    // SortKey, SortCreated или SortClicks, пустой - по ключу
+	Desc   bool      // в обратном порядке
+	After  *Cursor   // продолжение выдачи после позиции
+	Limit  int       // максимальное число записей, 0 - без ограничения
+}
+
+// Match - соответствие записи условиям поиска.
+func (q Query) Match(rec Record) bool {
+	now := q.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return strings.Contains(rec.ShortURL, q.Key) &&
+		strings.Contains(rec.OriginalURL, q.URL) &&
+		(q.Owner == "" || rec.UserID == q.Owner) &&
+		(q.Tag == "" || slices.Contains(rec.Tags, q.Tag)) &&
+		(len(q.Status) == 0 || slices.Contains(q.Status, Status(rec, now))) &&
+		(q.From.IsZero() || !rec.CreatedAt.Before(q.From)) &&
+		(q.To.IsZero() || rec.CreatedAt.Before(q.To)) &&
+		matchText(rec, q.Text)
+}
+
+// matchText - встречается ли каждое слово text в заголовке или исходном URL записи.
+func matchText(rec Record, text string) bool {
+	terms := Terms(text)
+	if len(terms) == 0 {
+		return true
+	}
+	words := recordWords(rec)
+	for _, term := range terms {
+		if !slices.Contains(words, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// Terms - слова текста для полнотекстового поиска: последовательности букв и цифр
+// в нижнем регистре, без повторов.
+func Terms(text string) []string {
+	var terms []string
+	for _, term := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// recordWords - слова заголовка и исходного URL записи.
+func recordWords(rec Record) []string {
+	return Terms(rec.Title + " " + rec.OriginalURL)
+}
+
+// Stats - статистика хранилища.
+type Stats struct {
+	URLs  int // действующие ссылки
+	Users int // пользователи, создавшие хотя бы одну ссылку
+}
+
+// Repository - хранилище сокращённых ссылок.
+type Repository interface {
+	// Find - поиск записи по ключу.
+	Find(ctx context.Context, key string) (Record, error)
+	// FindByURL - поиск действующей записи по исходному URL.
+	FindByURL(ctx context.Context, originalURL string) (Record, error)
+	// Create - добавление новой записи.
+	Create(ctx context.Context, rec Record) error
+	// FindByUser - действующие записи пользователя.
+	FindByUser(ctx context.Context, userID string) ([]Record, error)
+	// MarkDeleted - пометка записи как удалённой.
+	MarkDeleted(ctx context.Context, key string) error
+	// Update - атомарное изменение записи функцией fn. Ключ записи не меняется.
+	// Ошибка fn отменяет изменение и возвращается как есть.
+	Update(ctx context.Context, key string, fn func(rec *Record) error) (Record, error)
+	// Search - страница записей, включая удалённые, по условиям q в порядке выдачи q.
+	Search(ctx context.Context, q Query) (Page, error)
+	// Click - учёт перехода по ссылке: атомарное увеличение счётчика переходов.
+	// Если допустимое число переходов исчерпано, счётчик не меняется и возвращается ErrExhausted.
+	Click(ctx context.Context, key string) (Record, error)
+	// Stats - статистика хранилища.
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// Store - хранилище сервиса: репозиторий со снимками в файл формата хранилища.
+type Store interface {
+	Repository
+	// ForEachKey - обход ключей всех записей, включая удалённые.
+	ForEachKey(ctx context.Context, fn func(key string) error) error
+	// SaveToFile - запись снимка всех данных в файл.
+	SaveToFile(filePath string) error
+	// Close - освобождение ресурсов хранилища.
+	Close() error
+}
+
 type DB struct {
-	data  map[string]string
-	count int
+	data      map[string]Record
+	urls      map[string]string // исходный URL -> ключ
+	count     int
+	index     *index          // поиск по словам и меткам
+	protected map[string]bool // файлы, которые не удалось загрузить
+	keys      *Keyring        // ключи шифрования файла
 }
 
 // New - создание нового объекта БД.
 func New() *DB {
 	return &DB{
-		data:  make(map[string]string),
-		count: 0,
+		data:      make(map[string]Record),
+		urls:      make(map[string]string),
+		count:     0,
+		index:     newIndex(),
+		protected: make(map[string]bool),
 	}
 }
 
@@ -28,8 +190,8 @@ func (db *DB) Get(key string) (string, bool) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	value, exists := db.data[key]
-	return value, exists
+	rec, exists := db.data[key]
+	return rec.OriginalURL, exists
 }
 
 // Set - установка значения по ключу.
@@ -37,8 +199,17 @@ func (db *DB) Set(key, value string) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	_, exists := db.data[key]
-	db.data[key] = value
+	old, exists := db.data[key]
+	if exists && db.urls[old.OriginalURL] == key {
+		delete(db.urls, old.OriginalURL)
+	}
+	if exists {
+		db.index.remove(old)
+	}
+	rec := Record{ShortURL: key, OriginalURL: value}
+	db.data[key] = rec
+	db.urls[value] = key
+	db.index.add(rec)
 	if !exists {
 		db.count++
 	}
@@ -48,10 +219,14 @@ func (db *DB) Delete(key string) error {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	_, exists := db.data[key]
+	rec, exists := db.data[key]
 	if !exists {
-		return fmt.Errorf("key not found")
+		return ErrNotFound
+	}
+	if db.urls[rec.OriginalURL] == key {
+		delete(db.urls, rec.OriginalURL)
 	}
+	db.index.remove(rec)
 	delete(db.data, key)
 	db.count--
 	return nil
@@ -64,72 +239,236 @@ func (db *DB) Count() int {
 	return db.count
 }
 
-// record - запись об URLs.
-type record struct {
-	ID          int
-	ShortURL    string
-	OriginalURL string
+// ForEachKey - обход ключей всех записей. fn вызывается вне блокировки и может обращаться к БД.
+func (db *DB) ForEachKey(ctx context.Context, fn func(key string) error) error {
+	mutex.Lock()
+	keys := make([]string, 0, len(db.data))
+	for key := range db.data {
+		keys = append(keys, key)
+	}
+	mutex.Unlock()
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close - данные в памяти, освобождать нечего.
+func (db *DB) Close() error {
+	return nil
+}
+
+// Put - запись с заменой существующей, без проверок уникальности.
+func (db *DB) Put(rec Record) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	old, exists := db.data[rec.ShortURL]
+	if exists && db.urls[old.OriginalURL] == rec.ShortURL {
+		delete(db.urls, old.OriginalURL)
+	}
+	if exists {
+		db.index.remove(old)
+	}
+	db.data[rec.ShortURL] = rec
+	if !rec.DeletedFlag {
+		db.urls[rec.OriginalURL] = rec.ShortURL
+	}
+	db.index.add(rec)
+	if !exists {
+		db.count++
+	}
 }
 
-// SaveToFile - сохранение данных в JSON файл.
-func (db *DB) SaveToFile(filePath string) error {
+// Records - все записи, включая удалённые, упорядоченные по ключу.
+func (db *DB) Records() []Record {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	var records []record
+	return db.records()
+}
 
-	counter := 0
-	for shortURL, originalURL := range db.data {
-		counter++
-		record := record{counter, shortURL, originalURL}
-		records = append(records, record)
+// records - все записи без блокировки.
+func (db *DB) records() []Record {
+	records := make([]Record, 0, len(db.data))
+	for _, rec := range db.data {
+		records = append(records, rec)
 	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ShortURL < records[j].ShortURL })
+	return records
+}
 
-	data, err := json.MarshalIndent(records, "", "  ")
-	if err != nil {
-		return err
+// Find - поиск записи по ключу.
+func (db *DB) Find(_ context.Context, key string) (Record, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	rec, exists := db.data[key]
+	if !exists {
+		return Record{}, ErrNotFound
 	}
+	return rec, nil
+}
+
+// FindByURL - поиск действующей записи по исходному URL.
+func (db *DB) FindByURL(_ context.Context, originalURL string) (Record, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
 
-	// Создаем директорию, если её нет
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
+	key, exists := db.urls[originalURL]
+	if !exists {
+		return Record{}, ErrNotFound
 	}
+	return db.data[key], nil
+}
 
-	return os.WriteFile(filePath, data, 0644)
+// Create - добавление новой записи. Ключ и действующий исходный URL должны быть уникальны.
+func (db *DB) Create(_ context.Context, rec Record) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, exists := db.data[rec.ShortURL]; exists {
+		return ErrKeyExists
+	}
+	if _, exists := db.urls[rec.OriginalURL]; exists {
+		return ErrURLExists
+	}
+
+	db.data[rec.ShortURL] = rec
+	if !rec.DeletedFlag {
+		db.urls[rec.OriginalURL] = rec.ShortURL
+	}
+	db.index.add(rec)
+	db.count++
+	return nil
 }
 
-// LoadFromFile - загрузка данных из JSON файла.
-func (db *DB) LoadFromFile(filePath string) error {
+// MarkDeleted - пометка записи как удалённой. Запись остаётся в хранилище.
+func (db *DB) MarkDeleted(_ context.Context, key string) error {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	if _, err := os.Stat(filePath); os.IsNotExist(err) { // файла не существует
-		return err
+	rec, exists := db.data[key]
+	if !exists {
+		return ErrNotFound
+	}
+	if db.urls[rec.OriginalURL] == key {
+		delete(db.urls, rec.OriginalURL)
 	}
+	rec.DeletedFlag = true
+	db.data[key] = rec
+	return nil
+}
+
+// Update - атомарное изменение записи функцией fn. Ключ записи не меняется.
+func (db *DB) Update(_ context.Context, key string, fn func(rec *Record) error) (Record, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
 
-	bytes, err := os.ReadFile(filePath) // ошибка чтения файла
+	old, exists := db.data[key]
+	if !exists {
+		return Record{}, ErrNotFound
+	}
+
+	rec := old
+	if err := fn(&rec); err != nil {
+		return Record{}, err
+	}
+	rec.ShortURL = key
+
+	if other, exists := db.urls[rec.OriginalURL]; !rec.DeletedFlag && exists && other != key {
+		return Record{}, ErrURLExists
+	}
+
+	if db.urls[old.OriginalURL] == key {
+		delete(db.urls, old.OriginalURL)
+	}
+	db.index.remove(old)
+	db.data[key] = rec
+	if !rec.DeletedFlag {
+		db.urls[rec.OriginalURL] = key
+	}
+	db.index.add(rec)
+	return rec, nil
+}
+
+// Search - страница записей, включая удалённые, по условиям q в порядке выдачи q.
+// Условия по владельцу, меткам и словам сначала сужают выборку по индексу,
+// из оставшихся записей отбираются только попадающие на страницу.
+func (db *DB) Search(_ context.Context, q Query) (Page, error) {
+	pager, err := NewPager(q)
 	if err != nil {
-		return err
+		return Page{}, err
 	}
 
-	if len(bytes) == 0 { // пустой файл
-		return fmt.Errorf("empty file")
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if keys, ok := db.index.lookup(q.Owner, q.Tag, q.Text); ok {
+		for _, key := range keys {
+			pager.Add(db.data[key])
+		}
+	} else {
+		for _, rec := range db.data {
+			pager.Add(rec)
+		}
 	}
+	return pager.Page(), nil
+}
 
-	var records []record
+// Click - учёт перехода по ссылке.
+func (db *DB) Click(_ context.Context, key string) (Record, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
 
-	if err := json.Unmarshal(bytes, &records); err != nil { // ошибка десериализации JSON
-		return err
+	rec, exists := db.data[key]
+	if !exists {
+		return Record{}, ErrNotFound
+	}
+	if rec.Exhausted() {
+		return Record{}, ErrExhausted
 	}
+	rec.Clicks++
+	db.data[key] = rec
+	return rec, nil
+}
 
-	data := make(map[string]string)
-	for _, record := range records {
-		data[record.ShortURL] = record.OriginalURL
+// FindByUser - действующие записи пользователя.
+func (db *DB) FindByUser(_ context.Context, userID string) ([]Record, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var records []Record
+	for key := range db.index.owners[userID] {
+		if rec := db.data[key]; !rec.DeletedFlag {
+			records = append(records, rec)
+		}
 	}
+	return records, nil
+}
 
-	db.data = data
-	db.count = len(data)
+// Stats - статистика хранилища.
+func (db *DB) Stats(_ context.Context) (Stats, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
 
-	return nil
+	users := make(map[string]struct{})
+	var stats Stats
+	for _, rec := range db.data {
+		if rec.DeletedFlag {
+			continue
+		}
+		stats.URLs++
+		if rec.UserID != "" {
+			users[rec.UserID] = struct{}{}
+		}
+	}
+	stats.Users = len(users)
+	return stats, nil
 }
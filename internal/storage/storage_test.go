@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"os"
 	"sync"
 	"testing"
@@ -61,6 +63,139 @@ func TestDB(t *testing.T) {
 	})
 }
 
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create and find", func(t *testing.T) {
+		db := New()
+
+		err := db.Create(ctx, Record{ShortURL: "key1", OriginalURL: "https://example.com"})
+		require.NoError(t, err)
+
+		rec, err := db.Find(ctx, "key1")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com", rec.OriginalURL)
+
+		rec, err = db.FindByURL(ctx, "https://example.com")
+		require.NoError(t, err)
+		require.Equal(t, "key1", rec.ShortURL)
+
+		_, err = db.Find(ctx, "nonexistent")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("create duplicates", func(t *testing.T) {
+		db := New()
+		require.NoError(t, db.Create(ctx, Record{ShortURL: "key1", OriginalURL: "https://example.com"}))
+
+		err := db.Create(ctx, Record{ShortURL: "key1", OriginalURL: "https://google.com"})
+		require.ErrorIs(t, err, ErrKeyExists)
+
+		err = db.Create(ctx, Record{ShortURL: "key2", OriginalURL: "https://example.com"})
+		require.ErrorIs(t, err, ErrURLExists)
+		require.Equal(t, 1, db.Count())
+	})
+
+	t.Run("mark deleted", func(t *testing.T) {
+		db := New()
+		require.NoError(t, db.Create(ctx, Record{ShortURL: "key1", OriginalURL: "https://example.com"}))
+
+		require.NoError(t, db.MarkDeleted(ctx, "key1"))
+		rec, err := db.Find(ctx, "key1")
+		require.NoError(t, err)
+		require.True(t, rec.DeletedFlag)
+
+		_, err = db.FindByURL(ctx, "https://example.com")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		require.ErrorIs(t, db.MarkDeleted(ctx, "nonexistent"), ErrNotFound)
+	})
+
+	t.Run("put and records", func(t *testing.T) {
+		db := New()
+		db.Put(Record{ShortURL: "b", OriginalURL: "https://example.com", UserID: "user1"})
+		db.Put(Record{ShortURL: "a", OriginalURL: "https://google.com", DeletedFlag: true})
+		db.Put(Record{ShortURL: "b", OriginalURL: "https://github.com"})
+
+		require.Equal(t, 2, db.Count())
+		require.Equal(t, []Record{
+			{ShortURL: "a", OriginalURL: "https://google.com", DeletedFlag: true},
+			{ShortURL: "b", OriginalURL: "https://github.com"},
+		}, db.Records())
+
+		_, err := db.FindByURL(ctx, "https://example.com")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		db := New()
+		require.NoError(t, db.Create(ctx, Record{ShortURL: "a", OriginalURL: "https://example.com", UserID: "user1"}))
+		require.NoError(t, db.Create(ctx, Record{ShortURL: "b", OriginalURL: "https://google.com"}))
+
+		rec, err := db.Update(ctx, "a", func(rec *Record) error {
+			rec.ShortURL = "ignored"
+			rec.OriginalURL = "https://github.com"
+			rec.Disabled = true
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, Record{ShortURL: "a", OriginalURL: "https://github.com", UserID: "user1", Disabled: true}, rec)
+
+		_, err = db.FindByURL(ctx, "https://example.com")
+		require.ErrorIs(t, err, ErrNotFound)
+		rec, err = db.FindByURL(ctx, "https://github.com")
+		require.NoError(t, err)
+		require.Equal(t, "a", rec.ShortURL)
+
+		_, err = db.Update(ctx, "a", func(rec *Record) error {
+			rec.OriginalURL = "https://google.com"
+			return nil
+		})
+		require.ErrorIs(t, err, ErrURLExists)
+
+		errAbort := errors.New("abort")
+		_, err = db.Update(ctx, "a", func(rec *Record) error { return errAbort })
+		require.ErrorIs(t, err, errAbort)
+
+		_, err = db.Update(ctx, "nonexistent", func(rec *Record) error { return nil })
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		db := New()
+		db.Put(Record{ShortURL: "abc", OriginalURL: "https://example.com/spam", UserID: "user1"})
+		db.Put(Record{ShortURL: "abd", OriginalURL: "https://google.com", UserID: "user2", DeletedFlag: true})
+		db.Put(Record{ShortURL: "xyz", OriginalURL: "https://spam.example", UserID: "user1"})
+
+		page, err := db.Search(ctx, Query{Key: "ab"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 2)
+		require.Equal(t, "abc", page.Records[0].ShortURL)
+
+		page, err = db.Search(ctx, Query{URL: "spam", Owner: "user1", Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, []Record{{ShortURL: "abc", OriginalURL: "https://example.com/spam", UserID: "user1"}}, page.Records)
+
+		page, err = db.Search(ctx, Query{Owner: "user2"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.True(t, page.Records[0].DeletedFlag)
+	})
+
+	t.Run("for each key", func(t *testing.T) {
+		db := New()
+		db.Put(Record{ShortURL: "a", OriginalURL: "https://example.com"})
+		db.Put(Record{ShortURL: "b", OriginalURL: "https://google.com", DeletedFlag: true})
+
+		var keys []string
+		require.NoError(t, db.ForEachKey(ctx, func(key string) error {
+			keys = append(keys, key)
+			return nil
+		}))
+		require.ElementsMatch(t, []string{"a", "b"}, keys)
+	})
+}
+
 func TestDBConcurrent(t *testing.T) {
 	t.Run("concurrent set operations", func(t *testing.T) {
 		db := New()
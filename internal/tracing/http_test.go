@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := record(t)
+
+	passthrough := func(next http.Handler) http.Handler { return next }
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Use(Wrap("test", passthrough))
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, trace.SpanContextFromContext(r.Context()).IsValid())
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	req := httptest.NewRequest("GET", "/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	mw, server := spans[0], spans[1]
+	require.Equal(t, "middleware test", mw.Name())
+	require.Equal(t, server.SpanContext().SpanID(), mw.Parent().SpanID())
+
+	require.Equal(t, "GET /{id}", server.Name())
+	require.Equal(t, trace.SpanKindServer, server.SpanKind())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	require.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", http.StatusTemporaryRedirect))
+}
@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Store - декоратор хранилища, создающий спан на каждую операцию.
+type Store struct {
+	storage.Store
+}
+
+// Instrument - обёртка хранилища со спанами операций.
+func Instrument(store storage.Store) *Store {
+	return &Store{Store: store}
+}
+
+// start - спан операции op с атрибутами attrs.
+func start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer("internal/storage").Start(ctx, "storage."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+// finish - завершение спана с отметкой ошибки. Отсутствие ключа и исчерпанный лимит переходов ошибками не считаются.
+func finish(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, storage.ErrExhausted) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *Store) Find(ctx context.Context, key string) (storage.Record, error) {
+	ctx, span := start(ctx, "Find", attribute.String("shortener.key", key))
+	rec, err := s.Store.Find(ctx, key)
+	span.SetAttributes(attribute.Bool("shortener.found", err == nil))
+	finish(span, err)
+	return rec, err
+}
+
+func (s *Store) FindByURL(ctx context.Context, originalURL string) (storage.Record, error) {
+	ctx, span := start(ctx, "FindByURL")
+	rec, err := s.Store.FindByURL(ctx, originalURL)
+	span.SetAttributes(attribute.Bool("shortener.found", err == nil))
+	finish(span, err)
+	return rec, err
+}
+
+func (s *Store) FindByUser(ctx context.Context, userID string) ([]storage.Record, error) {
+	ctx, span := start(ctx, "FindByUser", attribute.String("shortener.user_id", userID))
+	records, err := s.Store.FindByUser(ctx, userID)
+	span.SetAttributes(attribute.Int("shortener.records", len(records)))
+	finish(span, err)
+	return records, err
+}
+
+func (s *Store) Create(ctx context.Context, rec storage.Record) error {
+	ctx, span := start(ctx, "Create", attribute.String("shortener.key", rec.ShortURL))
+	err := s.Store.Create(ctx, rec)
+	finish(span, err)
+	return err
+}
+
+func (s *Store) MarkDeleted(ctx context.Context, key string) error {
+	ctx, span := start(ctx, "MarkDeleted", attribute.String("shortener.key", key))
+	err := s.Store.MarkDeleted(ctx, key)
+	finish(span, err)
+	return err
+}
+
+func (s *Store) Update(ctx context.Context, key string, fn func(rec *storage.Record) error) (storage.Record, error) {
+	ctx, span := start(ctx, "Update", attribute.String("shortener.key", key))
+	rec, err := s.Store.Update(ctx, key, fn)
+	finish(span, err)
+	return rec, err
+}
+
+func (s *Store) Search(ctx context.Context, q storage.Query) (storage.Page, error) {
+	ctx, span := start(ctx, "Search", attribute.String("shortener.sort", q.Sort))
+	page, err := s.Store.Search(ctx, q)
+	span.SetAttributes(attribute.Int("shortener.records", len(page.Records)))
+	finish(span, err)
+	return page, err
+}
+
+func (s *Store) Click(ctx context.Context, key string) (storage.Record, error) {
+	ctx, span := start(ctx, "Click", attribute.String("shortener.key", key))
+	rec, err := s.Store.Click(ctx, key)
+	finish(span, err)
+	return rec, err
+}
+
+func (s *Store) Stats(ctx context.Context) (storage.Stats, error) {
+	ctx, span := start(ctx, "Stats")
+	stats, err := s.Store.Stats(ctx)
+	finish(span, err)
+	return stats, err
+}
+
+func (s *Store) ForEachKey(ctx context.Context, fn func(key string) error) error {
+	ctx, span := start(ctx, "ForEachKey")
+	err := s.Store.ForEachKey(ctx, fn)
+	finish(span, err)
+	return err
+}
@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestStore(t *testing.T) {
+	recorder := record(t)
+	ctx := context.Background()
+	db := Instrument(storage.New())
+
+	require.NoError(t, db.Create(ctx, storage.Record{ShortURL: "key", OriginalURL: "https://example.com"}))
+	require.ErrorIs(t, db.Create(ctx, storage.Record{ShortURL: "key", OriginalURL: "https://google.com"}), storage.ErrKeyExists)
+	_, err := db.Find(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	require.Equal(t, "storage.Create", spans[0].Name())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, "storage.Find", spans[2].Name())
+	require.Equal(t, codes.Unset, spans[2].Status().Code)
+}
@@ -0,0 +1,118 @@
+// Package tracing - трассировка OpenTelemetry: настройка экспорта, спаны HTTP и хранилища.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортёры спанов.
+const (
+	ExporterNone   = "none"   // трассировка отключена
+	ExporterStdout = "stdout" // JSON в стандартный вывод
+	ExporterFile   = "file"   // JSON в файл
+	ExporterOTLP   = "otlp"   // OTLP/HTTP в коллектор
+)
+
+// Имя сервиса в ресурсе трассировки.
+const serviceName = "url-shortener"
+
+// Config - параметры трассировки.
+type Config struct {
+	Exporter    string  // ExporterNone, ExporterStdout, ExporterFile или ExporterOTLP
+	File        string  // файл для ExporterFile
+	Endpoint    string  // URL коллектора для ExporterOTLP, по умолчанию из OTEL_EXPORTER_OTLP_ENDPOINT
+	SampleRatio float64 // доля трассируемых запросов без входящего решения
+}
+
+// Tracer - трассировщик пакета scope через глобальный провайдер.
+// Запрашивается при каждом использовании, чтобы учитывать смену провайдера после запуска.
+func Tracer(scope string) trace.Tracer {
+	return otel.Tracer("github.com/ParkhomenkoDV/URLShortener/" + scope)
+}
+
+// Setup - установка глобального провайдера и W3C-пропагатора.
+// Возвращает функцию, которая выгружает оставшиеся спаны и освобождает ресурсы.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Exporter == "" || cfg.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var closer io.Closer
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterFile:
+		var file *os.File
+		file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	// closeFile - закрытие файла трассировок, если настройка не удалась
+	closeFile := func() {
+		if closer != nil {
+			closer.Close()
+		}
+	}
+	if err != nil {
+		closeFile()
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		exporter.Shutdown(ctx)
+		closeFile()
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,374 @@
+// Package client - клиент HTTP API сервиса сокращения ссылок.
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Имя cookie, которой сервер идентифицирует пользователя.
+const authCookieName = "user_id"
+
+// Заголовок с курсором следующей страницы выдачи.
+const nextCursorHeader = "X-Next-Cursor"
+
+var (
+	// ErrConflict - URL уже был сокращён, возвращается вместе с существующей ссылкой.
+	ErrConflict = errors.New("client: URL already shortened")
+	// ErrNotFound - ссылка не найдена.
+	ErrNotFound = errors.New("client: URL not found")
+	// ErrGone - ссылка удалена.
+	ErrGone = errors.New("client: URL deleted")
+)
+
+// APIError - неожиданный ответ сервера.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("client: unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// BatchItem - элемент пакетного запроса.
+type BatchItem struct {
+	CorrelationID string `json:"correlation_id"`
+	OriginalURL   string `json:"original_url"`
+}
+
+// BatchResult - элемент ответа на пакетный запрос.
+type BatchResult struct {
+	CorrelationID string `json:"correlation_id"`
+	ShortURL      string `json:"short_url"`
+}
+
+// URL - ссылка пользователя.
+type URL struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+}
+
+// Stats - статистика сервиса.
+type Stats struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+}
+
+// Client - клиент сервиса сокращения ссылок.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	adminToken string
+	authCookie string
+	retries    int
+	backoff    time.Duration
+}
+
+// Option - настройка клиента.
+type Option func(*Client)
+
+// WithHTTPClient - использование собственного HTTP-клиента.
+// Переходы по редиректам в нём отключаются, чтобы Expand мог прочитать Location.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey - аутентификация по API-ключу вместо cookie.
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithAdminToken - токен администратора для служебных запросов, например Stats.
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// WithAuthCookie - восстановление ранее выданной сервером cookie пользователя.
+func WithAuthCookie(value string) Option {
+	return func(c *Client) {
+		c.authCookie = value
+	}
+}
+
+// WithRetries - число повторов при ответах 5xx и начальная задержка между ними.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// New - создание клиента для сервиса с адресом baseURL.
+func New(baseURL string, opts ...Option) *Client {
+	jar, _ := cookiejar.New(nil) // ошибка возможна только при заданных опциях
+
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Jar: jar, Timeout: 30 * time.Second},
+		retries:    3,
+		backoff:    100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.httpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	if c.authCookie != "" && c.httpClient.Jar != nil {
+		if u, err := url.Parse(c.baseURL); err == nil {
+			c.httpClient.Jar.SetCookies(u, []*http.Cookie{{Name: authCookieName, Value: c.authCookie, Path: "/"}})
+		}
+	}
+
+	return c
+}
+
+// AuthCookie - текущее значение cookie пользователя, чтобы сохранить его между запусками.
+func (c *Client) AuthCookie() string {
+	if c.httpClient.Jar == nil {
+		return ""
+	}
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return ""
+	}
+	for _, cookie := range c.httpClient.Jar.Cookies(u) {
+		if cookie.Name == authCookieName {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// Shorten - сокращение URL. Если URL уже сокращён, возвращает существующую ссылку и ErrConflict.
+func (c *Client) Shorten(ctx context.Context, originalURL string) (string, error) {
+	var resp struct {
+		Result string `json:"result"`
+	}
+	status, err := c.do(ctx, http.MethodPost, "/api/shorten", map[string]string{"url": originalURL}, &resp,
+		http.StatusCreated, http.StatusConflict)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusConflict {
+		return resp.Result, ErrConflict
+	}
+	return resp.Result, nil
+}
+
+// ShortenBatch - пакетное сокращение URL.
+func (c *Client) ShortenBatch(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	var resp []BatchResult
+	if _, err := c.do(ctx, http.MethodPost, "/api/shorten/batch", items, &resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Expand - исходный URL по сокращённой ссылке или ключу. Редирект не выполняется,
+// подходит любой код 3xx с заголовком Location.
+func (c *Client) Expand(ctx context.Context, shortURL string) (string, error) {
+	path := "/" + strings.TrimPrefix(strings.TrimPrefix(shortURL, c.baseURL), "/")
+
+	var location string
+	status, err := c.send(ctx, http.MethodGet, path, nil, func(resp *http.Response) error {
+		location = resp.Header.Get("Location")
+		return nil
+	}, redirectStatuses...)
+	if err != nil {
+		return "", err
+	}
+	if location == "" {
+		return "", &APIError{StatusCode: status, Message: "no Location header"}
+	}
+	return location, nil
+}
+
+// redirectStatuses - коды 3xx, на которые сервер отвечает по сокращённой ссылке.
+var redirectStatuses = func() []int {
+	var codes []int
+	for code := http.StatusMultipleChoices; code < http.StatusBadRequest; code++ {
+		codes = append(codes, code)
+	}
+	return codes
+}()
+
+// ListMine - все ссылки текущего пользователя. Сервер отдаёт их страницами,
+// следующие страницы запрашиваются по курсору из заголовка X-Next-Cursor.
+func (c *Client) ListMine(ctx context.Context) ([]URL, error) {
+	var urls []URL
+	path := "/api/user/urls"
+	for {
+		var page []URL
+		var next string
+		_, err := c.send(ctx, http.MethodGet, path, nil, func(resp *http.Response) error {
+			next = resp.Header.Get(nextCursorHeader)
+			if resp.StatusCode == http.StatusNoContent {
+				return nil
+			}
+			return json.NewDecoder(resp.Body).Decode(&page)
+		}, http.StatusOK, http.StatusNoContent)
+		if err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, page...)
+		if next == "" {
+			return urls, nil
+		}
+		path = "/api/user/urls?cursor=" + url.QueryEscape(next)
+	}
+}
+
+// Delete - удаление ссылок текущего пользователя по ключам.
+func (c *Client) Delete(ctx context.Context, keys ...string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/user/urls", keys, nil, http.StatusAccepted)
+	return err
+}
+
+// Stats - статистика сервиса, требует токен администратора (WithAdminToken).
+func (c *Client) Stats(ctx context.Context) (Stats, error) {
+	var resp Stats
+	_, err := c.do(ctx, http.MethodGet, "/api/internal/stats", nil, &resp, http.StatusOK)
+	return resp, err
+}
+
+// do - JSON-запрос с разбором JSON-ответа в out.
+func (c *Client) do(ctx context.Context, method, path string, in, out any, expected ...int) (int, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return 0, err
+		}
+	}
+
+	return c.send(ctx, method, path, body, func(resp *http.Response) error {
+		if out == nil || resp.StatusCode == http.StatusNoContent {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}, expected...)
+}
+
+// send - выполнение запроса с повторами при ответах 5xx и ошибках сети.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, read func(*http.Response) error, expected ...int) (int, error) {
+	backoff := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		status, err := c.sendOnce(ctx, method, path, body, read, expected)
+		if !retryable(status, err) || attempt >= c.retries {
+			return status, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// bearer - токен для заголовка Authorization: служебные ручки защищены токеном администратора.
+func (c *Client) bearer(path string) string {
+	if strings.HasPrefix(path, "/api/internal/") {
+		return c.adminToken
+	}
+	return c.apiKey
+}
+
+// sendOnce - одна попытка запроса.
+func (c *Client) sendOnce(ctx context.Context, method, path string, body []byte, read func(*http.Response) error, expected []int) (int, error) {
+	var reqBody io.Reader
+	if body != nil {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err != nil {
+			return 0, err
+		}
+		if err := gz.Close(); err != nil {
+			return 0, err
+		}
+		reqBody = &buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	if token := c.bearer(path); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Заголовок Accept-Encoding задан явно, поэтому распаковка на стороне клиента
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return resp.StatusCode, err
+		}
+		if gz != nil {
+			defer gz.Close()
+			resp.Body = gz
+		} else {
+			resp.Body = http.NoBody
+		}
+	}
+
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			return resp.StatusCode, read(resp)
+		}
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return resp.StatusCode, ErrNotFound
+	case http.StatusGone:
+		return resp.StatusCode, ErrGone
+	}
+	return resp.StatusCode, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+}
+
+// retryable - стоит ли повторить запрос.
+func retryable(status int, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if status == 0 {
+		return err != nil
+	}
+	return status >= http.StatusInternalServerError
+}
@@ -0,0 +1,176 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ParkhomenkoDV/URLShortener/internal/config"
+	"github.com/ParkhomenkoDV/URLShortener/internal/logger"
+	"github.com/ParkhomenkoDV/URLShortener/internal/router"
+	"github.com/ParkhomenkoDV/URLShortener/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer - тестовый сервер с настоящим маршрутизатором.
+func newServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	require.NoError(t, logger.New(logger.Config{Level: "error"}))
+
+	cfg := &config.Config{SecretKey: "secret", AdminToken: "admin"}
+	srv := httptest.NewUnstartedServer(nil)
+	cfg.BaseURL = "http://" + srv.Listener.Addr().String()
+
+	var h http.Handler = router.New(cfg, storage.New())
+	if wrap != nil {
+		h = wrap(h)
+	}
+	srv.Config.Handler = h
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	srv := newServer(t, nil)
+	c := New(srv.URL, WithAdminToken("admin"))
+
+	shortURL, err := c.Shorten(ctx, "https://example.com")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(shortURL, srv.URL+"/"))
+
+	t.Run("conflict", func(t *testing.T) {
+		again, err := c.Shorten(ctx, "https://example.com")
+		require.ErrorIs(t, err, ErrConflict)
+		require.Equal(t, shortURL, again)
+	})
+
+	t.Run("expand", func(t *testing.T) {
+		originalURL, err := c.Expand(ctx, shortURL)
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com", originalURL)
+
+		_, err = c.Expand(ctx, "unknown")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("expand permanent redirect", func(t *testing.T) {
+		permanentURL, err := c.Shorten(ctx, "https://example.net")
+		require.NoError(t, err)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPatch,
+			srv.URL+"/api/urls/"+strings.TrimPrefix(permanentURL, srv.URL+"/"), strings.NewReader(`{"redirect_code":301}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := c.httpClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		originalURL, err := c.Expand(ctx, permanentURL)
+		require.NoError(t, err)
+		require.Equal(t, "https://example.net", originalURL)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		results, err := c.ShortenBatch(ctx, []BatchItem{
+			{CorrelationID: "1", OriginalURL: "https://google.com"},
+			{CorrelationID: "2", OriginalURL: "https://github.com"},
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.Equal(t, "1", results[0].CorrelationID)
+		require.Equal(t, "2", results[1].CorrelationID)
+	})
+
+	t.Run("list, stats and delete", func(t *testing.T) {
+		urls, err := c.ListMine(ctx)
+		require.NoError(t, err)
+		require.Len(t, urls, 4)
+
+		stranger, err := New(srv.URL).ListMine(ctx)
+		require.NoError(t, err)
+		require.Empty(t, stranger)
+
+		stats, err := c.Stats(ctx)
+		require.NoError(t, err)
+		require.Equal(t, Stats{URLs: 4, Users: 1}, stats)
+
+		_, err = New(srv.URL).Stats(ctx)
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+		restored, err := New(srv.URL, WithAuthCookie(c.AuthCookie())).ListMine(ctx)
+		require.NoError(t, err)
+		require.Len(t, restored, 4)
+
+		key := strings.TrimPrefix(shortURL, srv.URL+"/")
+		require.NoError(t, c.Delete(ctx, key))
+
+		_, err = c.Expand(ctx, key)
+		require.ErrorIs(t, err, ErrGone)
+	})
+}
+
+func TestClientListPages(t *testing.T) {
+	ctx := context.Background()
+	srv := newServer(t, nil)
+	c := New(srv.URL)
+
+	items := make([]BatchItem, 250)
+	for i := range items {
+		items[i] = BatchItem{CorrelationID: strconv.Itoa(i), OriginalURL: fmt.Sprintf("https://example.com/%d", i)}
+	}
+	_, err := c.ShortenBatch(ctx, items)
+	require.NoError(t, err)
+
+	urls, err := c.ListMine(ctx)
+	require.NoError(t, err)
+	require.Len(t, urls, len(items))
+
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		seen[u.OriginalURL] = true
+	}
+	require.Len(t, seen, len(items))
+}
+
+func TestClientRetries(t *testing.T) {
+	ctx := context.Background()
+
+	var failures atomic.Int32
+	failures.Store(2)
+	srv := newServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failures.Add(-1) >= 0 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	t.Run("retries on 5xx", func(t *testing.T) {
+		c := New(srv.URL, WithRetries(2, time.Millisecond))
+		_, err := c.Shorten(ctx, "https://example.com")
+		require.NoError(t, err)
+	})
+
+	t.Run("gives up after retries", func(t *testing.T) {
+		failures.Store(5)
+		c := New(srv.URL, WithRetries(1, time.Millisecond))
+		_, err := c.Stats(ctx)
+
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	})
+}